@@ -0,0 +1,50 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Int reads an integer environment variable, falling back to def when it is
+// unset or malformed.
+func Int(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %q, using %d", key, value, def)
+		return def
+	}
+	return n
+}
+
+// Duration reads a Go duration string such as "15m" or "72h".
+func Duration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %q, using %s", key, value, def)
+		return def
+	}
+	return d
+}
+
+func Bool(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %q, using %t", key, value, def)
+		return def
+	}
+	return b
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInt(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 5},
+		{"12", 12},
+		{"-1", -1},
+		{"twelve", 5},
+		{"1.5", 5},
+	}
+	for _, tt := range tests {
+		t.Setenv("CONFIG_TEST_INT", tt.value)
+		if got := Int("CONFIG_TEST_INT", 5); got != tt.want {
+			t.Errorf("Int with %q = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", time.Hour},
+		{"15m", 15 * time.Minute},
+		{"72h", 72 * time.Hour},
+		{"15", time.Hour},
+		{"soon", time.Hour},
+	}
+	for _, tt := range tests {
+		t.Setenv("CONFIG_TEST_DURATION", tt.value)
+		if got := Duration("CONFIG_TEST_DURATION", time.Hour); got != tt.want {
+			t.Errorf("Duration with %q = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBool(t *testing.T) {
+	tests := []struct {
+		value string
+		def   bool
+		want  bool
+	}{
+		{"", true, true},
+		{"", false, false},
+		{"true", false, true},
+		{"1", false, true},
+		{"false", true, false},
+		{"yes", true, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("CONFIG_TEST_BOOL", tt.value)
+		if got := Bool("CONFIG_TEST_BOOL", tt.def); got != tt.want {
+			t.Errorf("Bool with %q, default %t = %t, want %t", tt.value, tt.def, got, tt.want)
+		}
+	}
+}
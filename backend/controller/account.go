@@ -0,0 +1,434 @@
+package controller
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/config"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/mailer"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+)
+
+const accountDeletionConfirmationTTL = time.Hour
+
+func accountDeletionGracePeriod() time.Duration {
+	return config.Duration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+}
+
+func DeleteCurrentUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input model.AccountDeletionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if user.Password != "" && !middleware.CheckPasswordHash(input.Password, user.Password) {
+		http.Error(w, "Password is incorrect", http.StatusUnauthorized)
+		return
+	}
+
+	var reassignTo *uint
+	switch input.TaskStrategy {
+	case model.TaskStrategyAnonymize:
+	case model.TaskStrategyReassign:
+		if input.ReassignTo == 0 || input.ReassignTo == user.ID {
+			http.Error(w, "A different user to reassign tasks to is required", http.StatusBadRequest)
+			return
+		}
+		// Tasks follow the same rule as assignments, and unknown users, strangers
+		// and accounts being deleted all get the same answer.
+		shared, err := sharesWorkspace(user.ID, input.ReassignTo)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		var pending int64
+		if shared {
+			err = database.DB.Model(&model.User{}).
+				Where("id = ? AND deletion_scheduled_at IS NOT NULL", input.ReassignTo).
+				Count(&pending).Error
+			if err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+		}
+		if !shared || pending > 0 {
+			http.Error(w, "Tasks can only be reassigned to members of your workspaces", http.StatusBadRequest)
+			return
+		}
+		reassignTo = &input.ReassignTo
+	default:
+		http.Error(w, "Task strategy must be reassign or anonymize", http.StatusBadRequest)
+		return
+	}
+
+	// Without a password to re-enter, a bearer of the session must also
+	// control the account's email.
+	if user.Password == "" {
+		if input.ConfirmationToken == "" {
+			sendDeletionConfirmation(w, user)
+			return
+		}
+		confirmed, err := useDeletionConfirmation(user.ID, input.ConfirmationToken)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !confirmed {
+			http.Error(w, "Invalid or expired confirmation token", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	scheduledAt := time.Now().Add(accountDeletionGracePeriod())
+	err := database.DB.Model(&user).Updates(map[string]interface{}{
+		"deletion_scheduled_at":  scheduledAt,
+		"deletion_task_strategy": input.TaskStrategy,
+		"deletion_reassign_to":   reassignTo,
+	}).Error
+	if err != nil {
+		http.Error(w, "Could not schedule account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	sessionID, _ := r.Context().Value(middleware.SessionIDKey).(string)
+	if err := revokeOtherSessions(user.ID, sessionID); err != nil {
+		log.Printf("Could not revoke sessions for user %d: %v", user.ID, err)
+	}
+
+	mailer.SendAsync(user.Email, "Your account is scheduled for deletion", fmt.Sprintf(
+		"Hi %s,\n\nYour account and personal data will be deleted on %s UTC.\n"+
+			"Sign in and cancel the deletion from your profile before then if you change your mind.",
+		user.Name, scheduledAt.UTC().Format(time.RFC1123)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":      "Account scheduled for deletion",
+		"scheduled_at": scheduledAt,
+	})
+}
+
+// sendDeletionConfirmation emails a token that confirms deleting an account
+// that has no password.
+func sendDeletionConfirmation(w http.ResponseWriter, user model.User) {
+	token, err := randomString(32)
+	if err != nil {
+		http.Error(w, "Could not start account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	err = database.DB.Create(&model.AccountDeletionConfirmation{
+		UserID:    user.ID,
+		TokenHash: middleware.HashToken(token),
+		ExpiresAt: time.Now().Add(accountDeletionConfirmationTTL),
+	}).Error
+	if err != nil {
+		http.Error(w, "Could not start account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	mailer.SendAsync(user.Email, "Confirm deleting your account", fmt.Sprintf(
+		"Hi %s,\n\nSomeone asked to delete your account. If it was you, confirm by opening:\n%s\n\n"+
+			"The link expires in an hour. If it wasn't you, sign out of your other sessions.",
+		user.Name, appURL("/delete-account?token="+token)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":               "Check your email to confirm deleting the account",
+		"confirmation_required": true,
+	})
+}
+
+// useDeletionConfirmation redeems a deletion confirmation token. Tokens are
+// single use.
+func useDeletionConfirmation(userID uint, token string) (bool, error) {
+	now := time.Now()
+	result := database.DB.Model(&model.AccountDeletionConfirmation{}).
+		Where("user_id = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", userID, middleware.HashToken(token), now).
+		Update("used_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+func CancelAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result := database.DB.Model(&model.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userID).
+		Updates(map[string]interface{}{
+			"deletion_scheduled_at":  nil,
+			"deletion_task_strategy": "",
+			"deletion_reassign_to":   nil,
+		})
+	if result.Error != nil {
+		http.Error(w, "Could not cancel account deletion", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Account is not scheduled for deletion", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Account deletion cancelled"})
+}
+
+// PurgeDeletedAccounts permanently deletes accounts whose grace period has
+// passed. Tasks they created or were assigned are kept and either handed to
+// the chosen user or left without an owner.
+func PurgeDeletedAccounts() {
+	var users []model.User
+	if err := database.DB.Where("deletion_scheduled_at <= ?", time.Now()).Find(&users).Error; err != nil {
+		log.Printf("[Purge] Could not load accounts due for deletion: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if err := purgeUser(user); err != nil {
+			log.Printf("[Purge] Could not delete user %d: %v", user.ID, err)
+			continue
+		}
+		log.Printf("[Purge] Deleted user %d", user.ID)
+	}
+}
+
+func purgeUser(user model.User) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var replacement uint
+		if user.DeletionTaskStrategy == model.TaskStrategyReassign && user.DeletionReassignTo != nil {
+			// The chosen user may have been deleted in the meantime.
+			var count int64
+			tx.Model(&model.User{}).Where("id = ?", *user.DeletionReassignTo).Count(&count)
+			if count > 0 {
+				replacement = *user.DeletionReassignTo
+			}
+		}
+
+		if err := tx.Unscoped().Model(&model.Task{}).Where("created_by = ?", user.ID).Update("created_by", replacement).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.Task{}).Where("assigned_to = ?", user.ID).Update("assigned_to", replacement).Error; err != nil {
+			return err
+		}
+
+		// Comments stay in their threads but no longer point at the user.
+		if err := tx.Unscoped().Model(&model.Comment{}).Where("author_id = ?", user.ID).Update("author_id", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.CommentEdit{}).Where("edited_by = ?", user.ID).Update("edited_by", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Attachment{}).Where("uploaded_by = ?", user.ID).Update("uploaded_by", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Invitation{}).Where("invited_by = ?", user.ID).Update("invited_by", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Invitation{}).Where("accepted_by = ?", user.ID).Update("accepted_by", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.TaskAccess{}).Where("granted_by = ?", user.ID).Update("granted_by", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.TaskDependency{}).Where("created_by = ?", user.ID).Update("created_by", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Notification{}).Where("actor_id = ?", user.ID).Update("actor_id", 0).Error; err != nil {
+			return err
+		}
+
+		if err := handOverWorkspaces(tx, user.ID); err != nil {
+			return err
+		}
+
+		for _, related := range []interface{}{
+			&model.APIToken{},
+			&model.Session{},
+			&model.OIDCIdentity{},
+			&model.EmailVerification{},
+			&model.AccountDeletionConfirmation{},
+			&model.AISuggestionRecord{},
+			&model.LoginAttempt{},
+			&model.WorkspaceMember{},
+			&model.PasswordReset{},
+			&model.Notification{},
+			&model.TaskAccess{},
+			&model.DueSoonReminder{},
+			&model.TaskWatcher{},
+		} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(related).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("owner_id = ?", user.ID).Delete(&model.SavedView{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&user).Error
+	})
+}
+
+// purgeRequest stands in for the request behind changes made while purging
+// an account, which have no acting user.
+var purgeRequest = (&http.Request{}).WithContext(context.Background())
+
+// handOverWorkspaces passes each workspace the user owns to the member with
+// the highest role, the longest standing one on a tie. Workspaces nobody else
+// belongs to are deleted.
+func handOverWorkspaces(tx *gorm.DB, userID uint) error {
+	var workspaces []model.Workspace
+	if err := tx.Where("owner_id = ?", userID).Find(&workspaces).Error; err != nil {
+		return err
+	}
+
+	for _, workspace := range workspaces {
+		var members []model.WorkspaceMember
+		err := tx.Where("workspace_id = ? AND user_id <> ?", workspace.ID, userID).
+			Order("created_at, user_id").
+			Find(&members).Error
+		if err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			if err := deleteWorkspace(tx, workspace.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		successor := members[0]
+		for _, member := range members[1:] {
+			if model.WorkspaceRoleRank[member.Role] > model.WorkspaceRoleRank[successor.Role] {
+				successor = member
+			}
+		}
+		err = tx.Model(&model.WorkspaceMember{}).
+			Where("workspace_id = ? AND user_id = ?", workspace.ID, successor.UserID).
+			Update("role", model.WorkspaceRoleOwner).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&workspace).Update("owner_id", successor.UserID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteWorkspace removes a workspace with its invitations, shared views and
+// labels. Tasks lose the workspace's labels as a recorded change.
+func deleteWorkspace(tx *gorm.DB, workspaceID uint) error {
+	var labelIDs []uint
+	if err := tx.Model(&model.Label{}).Where("workspace_id = ?", workspaceID).Pluck("id", &labelIDs).Error; err != nil {
+		return err
+	}
+	if len(labelIDs) > 0 {
+		taskIDs, err := labelledTasks(tx, labelIDs...)
+		if err != nil {
+			return err
+		}
+		err = changeTaskLabels(tx, purgeRequest, taskIDs, func() error {
+			if err := tx.Exec("DELETE FROM task_labels WHERE label_id IN ?", labelIDs).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", labelIDs).Delete(&model.Label{}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, related := range []interface{}{
+		&model.Invitation{},
+		&model.SavedView{},
+		&model.WorkspaceMember{},
+	} {
+		if err := tx.Where("workspace_id = ?", workspaceID).Delete(related).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&model.Workspace{}, workspaceID).Error
+}
+
+func ExportCurrentUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	var tasks []model.Task
+	if err := database.DB.Unscoped().Where("assigned_to = ? OR created_by = ?", userID, userID).Order("id").Find(&tasks).Error; err != nil {
+		http.Error(w, "Could not retrieve tasks", http.StatusInternalServerError)
+		return
+	}
+
+	var history []model.AISuggestionRecord
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at").Find(&history).Error; err != nil {
+		http.Error(w, "Could not retrieve AI history", http.StatusInternalServerError)
+		return
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", userResponse(user)},
+		{"tasks.json", tasks},
+		{"ai_history.json", history},
+	}
+
+	filename := fmt.Sprintf("export-user-%d-%s.zip", user.ID, time.Now().UTC().Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			log.Printf("Export for user %d failed: %v", user.ID, err)
+			return
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			log.Printf("Export for user %d failed: %v", user.ID, err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		log.Printf("Export for user %d failed: %v", user.ID, err)
+	}
+}
@@ -0,0 +1,218 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+// create stores each of values, failing the test on the first error.
+func create(t *testing.T, values ...interface{}) {
+	t.Helper()
+	for _, value := range values {
+		if err := database.DB.Create(value).Error; err != nil {
+			t.Fatalf("create %T: %v", value, err)
+		}
+	}
+}
+
+func TestPurgeUserHandsOverWorkspaces(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	member := newUser(t, "Bob", "bob@example.com")
+	admin := newUser(t, "Cy", "cy@example.com")
+
+	shared := model.Workspace{Name: "Shared", OwnerID: owner.ID}
+	solo := model.Workspace{Name: "Solo", OwnerID: owner.ID}
+	create(t, &shared, &solo)
+	joined := time.Now().Add(-time.Hour)
+	create(t,
+		&model.WorkspaceMember{WorkspaceID: shared.ID, UserID: owner.ID, Role: model.WorkspaceRoleOwner, CreatedAt: joined},
+		&model.WorkspaceMember{WorkspaceID: shared.ID, UserID: member.ID, Role: model.WorkspaceRoleMember, CreatedAt: joined.Add(time.Minute)},
+		&model.WorkspaceMember{WorkspaceID: shared.ID, UserID: admin.ID, Role: model.WorkspaceRoleAdmin, CreatedAt: joined.Add(2 * time.Minute)},
+		&model.WorkspaceMember{WorkspaceID: solo.ID, UserID: owner.ID, Role: model.WorkspaceRoleOwner, CreatedAt: joined},
+	)
+
+	label := model.Label{WorkspaceID: solo.ID, Name: "urgent"}
+	create(t, &label)
+	task := model.Task{Title: "Ship it", Status: "pending", CreatedBy: member.ID, Labels: []model.Label{label}}
+	create(t, &task)
+
+	invitation := model.Invitation{WorkspaceID: shared.ID, Role: model.WorkspaceRoleMember, TokenHash: "invite", InvitedBy: owner.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	create(t, &invitation,
+		&model.Invitation{WorkspaceID: solo.ID, Role: model.WorkspaceRoleMember, TokenHash: "solo-invite", InvitedBy: owner.ID, ExpiresAt: time.Now().Add(time.Hour)},
+		&model.TaskAccess{TaskID: task.ID, UserID: member.ID, Reason: "shared", GrantedBy: owner.ID},
+	)
+
+	if err := purgeUser(owner); err != nil {
+		t.Fatalf("purgeUser: %v", err)
+	}
+
+	var count int64
+	database.DB.Model(&model.User{}).Where("id = ?", owner.ID).Count(&count)
+	if count != 0 {
+		t.Error("user still exists")
+	}
+
+	// The admin outranks the longer standing member.
+	database.DB.First(&shared, shared.ID)
+	if shared.OwnerID != admin.ID {
+		t.Errorf("shared workspace owner = %d, want %d", shared.OwnerID, admin.ID)
+	}
+	var successor model.WorkspaceMember
+	database.DB.Where("workspace_id = ? AND user_id = ?", shared.ID, admin.ID).First(&successor)
+	if successor.Role != model.WorkspaceRoleOwner {
+		t.Errorf("successor role = %q, want owner", successor.Role)
+	}
+
+	// Nobody else was in the solo workspace, so it goes with its labels.
+	for table, query := range map[string]string{
+		"workspaces":  "SELECT COUNT(*) FROM workspaces WHERE id = ?",
+		"labels":      "SELECT COUNT(*) FROM labels WHERE workspace_id = ?",
+		"invitations": "SELECT COUNT(*) FROM invitations WHERE workspace_id = ?",
+		"members":     "SELECT COUNT(*) FROM workspace_members WHERE workspace_id = ?",
+	} {
+		database.DB.Raw(query, solo.ID).Scan(&count)
+		if count != 0 {
+			t.Errorf("%d %s of the solo workspace remain", count, table)
+		}
+	}
+	database.DB.Raw("SELECT COUNT(*) FROM task_labels WHERE task_id = ?", task.ID).Scan(&count)
+	if count != 0 {
+		t.Error("the task kept the deleted label")
+	}
+	var stored model.Task
+	database.DB.First(&stored, task.ID)
+	if stored.Version != task.Version+1 {
+		t.Errorf("task version = %d, want %d", stored.Version, task.Version+1)
+	}
+
+	database.DB.First(&invitation, invitation.ID)
+	if invitation.InvitedBy != 0 {
+		t.Errorf("invitation still points at the deleted user")
+	}
+	var access model.TaskAccess
+	database.DB.Where("task_id = ? AND user_id = ?", task.ID, member.ID).First(&access)
+	if access.GrantedBy != 0 {
+		t.Errorf("task access still points at the deleted user")
+	}
+}
+
+func TestPurgeUserReassignsTasks(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+	heir := newUser(t, "Bob", "bob@example.com")
+	user.DeletionTaskStrategy = model.TaskStrategyReassign
+	user.DeletionReassignTo = &heir.ID
+
+	task := model.Task{Title: "Ship it", Status: "pending", CreatedBy: user.ID, AssignedTo: user.ID}
+	create(t, &task)
+
+	if err := purgeUser(user); err != nil {
+		t.Fatalf("purgeUser: %v", err)
+	}
+	database.DB.First(&task, task.ID)
+	if task.CreatedBy != heir.ID || task.AssignedTo != heir.ID {
+		t.Errorf("task created by %d and assigned to %d, want both %d", task.CreatedBy, task.AssignedTo, heir.ID)
+	}
+}
+
+func TestDeleteCurrentUserWithoutPassword(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+
+	deleteAccount := func(token string) int {
+		body := map[string]string{"task_strategy": model.TaskStrategyAnonymize, "confirmation_token": token}
+		return serve(DeleteCurrentUser, as(request(t, http.MethodDelete, "/api/users/me", body), user.ID, "session")).Code
+	}
+	scheduled := func() bool {
+		var stored model.User
+		database.DB.First(&stored, user.ID)
+		return stored.DeletionScheduledAt != nil
+	}
+
+	// The first request only emails a confirmation token.
+	if status := deleteAccount(""); status != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", status, http.StatusAccepted)
+	}
+	if scheduled() {
+		t.Fatal("deletion was scheduled without confirmation")
+	}
+
+	if status := deleteAccount("guessed"); status != http.StatusUnauthorized {
+		t.Errorf("wrong token: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	token := "confirm-" + randomTestString(t)
+	result := database.DB.Model(&model.AccountDeletionConfirmation{}).
+		Where("user_id = ?", user.ID).
+		Update("token_hash", middleware.HashToken(token))
+	if result.RowsAffected != 1 {
+		t.Fatalf("expected one confirmation, found %d", result.RowsAffected)
+	}
+
+	if status := deleteAccount(token); status != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", status, http.StatusAccepted)
+	}
+	if !scheduled() {
+		t.Error("confirmed deletion was not scheduled")
+	}
+	if status := deleteAccount(token); status != http.StatusUnauthorized {
+		t.Errorf("reused token: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestDeleteCurrentUserReassignTarget(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+	member := newUser(t, "Bob", "bob@example.com")
+	leaving := newUser(t, "Cy", "cy@example.com")
+	stranger := newUser(t, "Eve", "eve@example.com")
+	workspace := newWorkspace(t, user)
+	addMember(t, workspace, member, model.WorkspaceRoleMember)
+	addMember(t, workspace, leaving, model.WorkspaceRoleMember)
+	database.DB.Model(&leaving).Update("deletion_scheduled_at", time.Now())
+
+	reassign := func(to uint) int {
+		body := map[string]interface{}{"task_strategy": model.TaskStrategyReassign, "reassign_to": to}
+		return serve(DeleteCurrentUser, as(request(t, http.MethodDelete, "/api/users/me", body), user.ID, "session")).Code
+	}
+	for name, to := range map[string]uint{
+		"a stranger":             stranger.ID,
+		"an unknown user":        stranger.ID + 100,
+		"a user being deleted":   leaving.ID,
+		"the user being deleted": user.ID,
+	} {
+		if status := reassign(to); status != http.StatusBadRequest {
+			t.Errorf("reassigning to %s: status = %d, want %d", name, status, http.StatusBadRequest)
+		}
+	}
+
+	// Without a password the first request only emails a confirmation.
+	if status := reassign(member.ID); status != http.StatusAccepted {
+		t.Errorf("reassigning to a member: status = %d, want %d", status, http.StatusAccepted)
+	}
+}
+
+func TestDeleteCurrentUserChecksPassword(t *testing.T) {
+	dbtest.Open(t)
+	hash, err := middleware.HashPassword("secret password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := newUser(t, "Ada", "ada@example.com")
+	database.DB.Model(&user).Update("password", hash)
+
+	body := map[string]string{"task_strategy": model.TaskStrategyAnonymize, "password": "wrong password"}
+	rec := serve(DeleteCurrentUser, as(request(t, http.MethodDelete, "/api/users/me", body), user.ID, "session"))
+	expect(t, rec, http.StatusUnauthorized)
+
+	body["password"] = "secret password"
+	rec = serve(DeleteCurrentUser, as(request(t, http.MethodDelete, "/api/users/me", body), user.ID, "session"))
+	expect(t, rec, http.StatusAccepted)
+}
@@ -0,0 +1,349 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/mailer"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetTTL = 24 * time.Hour
+	impersonationTTL = time.Hour
+)
+
+func adminUserResponse(user model.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                      user.ID,
+		"name":                    user.Name,
+		"email":                   user.Email,
+		"email_verified":          user.EmailVerified,
+		"role":                    user.Role,
+		"disabled_at":             user.DisabledAt,
+		"password_reset_required": user.PasswordResetRequired,
+		"locked_until":            user.LockedUntil,
+		"deletion_scheduled_at":   user.DeletionScheduledAt,
+	}
+}
+
+// parsePagination reads limit and offset query parameters with sane bounds.
+func parsePagination(r *http.Request, defaultLimit, maxLimit int) (int, int) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func recordAdminAction(r *http.Request, actorID uint, action string, targetUserID uint, details string) {
+	entry := model.AdminAuditLog{
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: &targetUserID,
+		Details:      details,
+		IP:           middleware.ClientIP(r),
+	}
+	if err := database.DB.Create(&entry).Error; err != nil {
+		log.Printf("Could not record admin action %s: %v", action, err)
+	}
+}
+
+// loadAdminTarget loads the user named in the path and writes an error
+// response if it does not exist.
+func loadAdminTarget(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+	var user model.User
+	if err := database.DB.Where("id = ?", r.PathValue("id")).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &user, true
+}
+
+func AdminListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r, 50, 200)
+
+	query := database.DB.Model(&model.User{})
+	if q := r.URL.Query().Get("q"); q != "" {
+		pattern := "%" + q + "%"
+		query = query.Where("name ILIKE ? OR email ILIKE ?", pattern, pattern)
+	}
+	switch r.URL.Query().Get("status") {
+	case "disabled":
+		query = query.Where("disabled_at IS NOT NULL")
+	case "active":
+		query = query.Where("disabled_at IS NULL")
+	}
+	if role := r.URL.Query().Get("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		http.Error(w, "Could not retrieve users", http.StatusInternalServerError)
+		return
+	}
+
+	var users []model.User
+	if err := query.Order("id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		http.Error(w, "Could not retrieve users", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]map[string]interface{}, 0, len(users))
+	for _, user := range users {
+		response = append(response, adminUserResponse(user))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"users": response, "total": total})
+}
+
+func AdminGetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := loadAdminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": adminUserResponse(*user)})
+}
+
+func AdminGetUserTasks(w http.ResponseWriter, r *http.Request) {
+	user, ok := loadAdminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var tasks []model.Task
+	if err := database.DB.Where("assigned_to = ? OR created_by = ?", user.ID, user.ID).Order("id").Find(&tasks).Error; err != nil {
+		http.Error(w, "Could not retrieve tasks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tasks": tasks})
+}
+
+func AdminDisableUser(w http.ResponseWriter, r *http.Request) {
+	adminID := r.Context().Value(middleware.UserIDKey).(uint)
+
+	user, ok := loadAdminTarget(w, r)
+	if !ok {
+		return
+	}
+	if user.ID == adminID {
+		http.Error(w, "You cannot disable your own account", http.StatusBadRequest)
+		return
+	}
+
+	var input model.AdminActionInput
+	json.NewDecoder(r.Body).Decode(&input)
+
+	now := time.Now()
+	if err := database.DB.Model(user).Update("disabled_at", now).Error; err != nil {
+		http.Error(w, "Could not disable user", http.StatusInternalServerError)
+		return
+	}
+	user.DisabledAt = &now
+	if err := revokeOtherSessions(user.ID, ""); err != nil {
+		log.Printf("Could not revoke sessions for user %d: %v", user.ID, err)
+	}
+	recordAdminAction(r, adminID, model.AuditActionDisableUser, user.ID, input.Reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": adminUserResponse(*user)})
+}
+
+func AdminEnableUser(w http.ResponseWriter, r *http.Request) {
+	adminID := r.Context().Value(middleware.UserIDKey).(uint)
+
+	user, ok := loadAdminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var input model.AdminActionInput
+	json.NewDecoder(r.Body).Decode(&input)
+
+	if err := database.DB.Model(user).Update("disabled_at", nil).Error; err != nil {
+		http.Error(w, "Could not enable user", http.StatusInternalServerError)
+		return
+	}
+	user.DisabledAt = nil
+	recordAdminAction(r, adminID, model.AuditActionEnableUser, user.ID, input.Reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": adminUserResponse(*user)})
+}
+
+// AdminForcePasswordReset signs the user out everywhere and blocks password
+// login until they set a new password through the emailed link.
+func AdminForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	adminID := r.Context().Value(middleware.UserIDKey).(uint)
+
+	user, ok := loadAdminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var input model.AdminActionInput
+	json.NewDecoder(r.Body).Decode(&input)
+
+	token, err := randomString(32)
+	if err != nil {
+		http.Error(w, "Could not create reset token", http.StatusInternalServerError)
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password_reset_required", true).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&model.PasswordReset{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.PasswordReset{
+			UserID:    user.ID,
+			TokenHash: middleware.HashToken(token),
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+		}).Error
+	})
+	if err != nil {
+		http.Error(w, "Could not force password reset", http.StatusInternalServerError)
+		return
+	}
+	user.PasswordResetRequired = true
+
+	if err := revokeOtherSessions(user.ID, ""); err != nil {
+		log.Printf("Could not revoke sessions for user %d: %v", user.ID, err)
+	}
+	recordAdminAction(r, adminID, model.AuditActionForcePasswordReset, user.ID, input.Reason)
+
+	mailer.SendAsync(user.Email, "Reset your password", fmt.Sprintf(
+		"Hi %s,\n\nAn administrator requires you to choose a new password before signing in again:\n%s\n\nThe link expires in 24 hours.",
+		user.Name, appURL("/reset-password?token="+token)))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": adminUserResponse(*user)})
+}
+
+// AdminImpersonateUser issues a short-lived session for the target user that
+// remembers which admin opened it. Writes made with it are audited.
+func AdminImpersonateUser(w http.ResponseWriter, r *http.Request) {
+	adminID := r.Context().Value(middleware.UserIDKey).(uint)
+
+	user, ok := loadAdminTarget(w, r)
+	if !ok {
+		return
+	}
+	if user.ID == adminID || user.Role == model.RoleAdmin {
+		http.Error(w, "Administrators cannot be impersonated", http.StatusBadRequest)
+		return
+	}
+	if user.DisabledAt != nil {
+		http.Error(w, "Account disabled", http.StatusBadRequest)
+		return
+	}
+
+	var input model.AdminActionInput
+	json.NewDecoder(r.Body).Decode(&input)
+
+	sessionID, err := randomString(16)
+	if err != nil {
+		http.Error(w, "Could not start impersonation", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	session := model.Session{
+		ID:             sessionID,
+		UserID:         user.ID,
+		UserAgent:      r.UserAgent(),
+		IP:             middleware.ClientIP(r),
+		LastSeenAt:     now,
+		ExpiresAt:      now.Add(impersonationTTL),
+		ImpersonatorID: &adminID,
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		http.Error(w, "Could not start impersonation", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := middleware.GenerateToken(user.ID, session.ID, session.ExpiresAt)
+	if err != nil {
+		http.Error(w, "Could not generate token", http.StatusInternalServerError)
+		return
+	}
+	recordAdminAction(r, adminID, model.AuditActionImpersonate, user.ID, input.Reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":       adminUserResponse(*user),
+		"token":      token,
+		"expires_at": session.ExpiresAt,
+	})
+}
+
+func AdminGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r, 50, 200)
+
+	query := database.DB.Model(&model.AdminAuditLog{})
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		query = query.Where("target_user_id = ? OR actor_id = ?", userID, userID)
+	}
+	if action := r.URL.Query().Get("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	var entries []model.AdminAuditLog
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		http.Error(w, "Could not retrieve audit log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"entries": entries})
+}
+
+func AdminGetLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r, 50, 200)
+
+	query := database.DB.Model(&model.LoginAttempt{})
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if ip := r.URL.Query().Get("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	if outcome := r.URL.Query().Get("outcome"); outcome != "" {
+		query = query.Where("outcome = ?", outcome)
+	}
+
+	var attempts []model.LoginAttempt
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&attempts).Error; err != nil {
+		http.Error(w, "Could not retrieve login attempts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"attempts": attempts})
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func newAdmin(t *testing.T) model.User {
+	t.Helper()
+	admin := newUser(t, "Root", "root@example.com")
+	database.DB.Model(&admin).Update("role", model.RoleAdmin)
+	admin.Role = model.RoleAdmin
+	return admin
+}
+
+// adminAction runs an admin handler against target as admin.
+func adminAction(t *testing.T, handler http.HandlerFunc, admin, target model.User) *httptest.ResponseRecorder {
+	t.Helper()
+	id := strconv.FormatUint(uint64(target.ID), 10)
+	r := as(request(t, http.MethodPost, "/api/admin/users/"+id, map[string]string{"reason": "testing"}), admin.ID, "session")
+	r.SetPathValue("id", id)
+	return serve(handler, r)
+}
+
+// withToken runs handler behind AuthMiddleware for a request bearing token.
+func withToken(t *testing.T, method, token string, handler http.HandlerFunc) int {
+	t.Helper()
+	r := request(t, method, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return serve(middleware.AuthMiddleware(handler), r).Code
+}
+
+func noContent(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func auditEntries(t *testing.T, action string, target model.User) int64 {
+	t.Helper()
+	var count int64
+	database.DB.Model(&model.AdminAuditLog{}).Where("action = ? AND target_user_id = ?", action, target.ID).Count(&count)
+	return count
+}
+
+func TestAdminDisableUser(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+	admin := newAdmin(t)
+	user := newUser(t, "Ada", "ada@example.com")
+	_, token := login(t, user)
+
+	expect(t, adminAction(t, AdminDisableUser, admin, admin), http.StatusBadRequest)
+	expect(t, adminAction(t, AdminDisableUser, admin, user), http.StatusOK)
+
+	if withToken(t, http.MethodGet, token, noContent) == http.StatusNoContent {
+		t.Error("the disabled user's session is still accepted")
+	}
+	_, fresh := login(t, user)
+	if status := withToken(t, http.MethodGet, fresh, noContent); status != http.StatusForbidden {
+		t.Errorf("new session of a disabled user: status = %d, want %d", status, http.StatusForbidden)
+	}
+	if auditEntries(t, model.AuditActionDisableUser, user) != 1 {
+		t.Error("disabling was not audited")
+	}
+
+	expect(t, adminAction(t, AdminEnableUser, admin, user), http.StatusOK)
+	if status := withToken(t, http.MethodGet, fresh, noContent); status != http.StatusNoContent {
+		t.Errorf("re-enabled user: status = %d, want %d", status, http.StatusNoContent)
+	}
+}
+
+func TestAdminImpersonateUser(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+	admin := newAdmin(t)
+	user := newUser(t, "Ada", "ada@example.com")
+
+	expect(t, adminAction(t, AdminImpersonateUser, admin, admin), http.StatusBadRequest)
+
+	rec := adminAction(t, AdminImpersonateUser, admin, user)
+	expect(t, rec, http.StatusOK)
+	var response struct {
+		Token string `json:"token"`
+	}
+	decode(t, rec, &response)
+
+	var impersonator uint
+	status := withToken(t, http.MethodPost, response.Token, func(w http.ResponseWriter, r *http.Request) {
+		impersonator, _ = r.Context().Value(middleware.ImpersonatorIDKey).(uint)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	if status != http.StatusNoContent || impersonator != admin.ID {
+		t.Fatalf("status = %d, impersonator = %d; want %d and %d", status, impersonator, http.StatusNoContent, admin.ID)
+	}
+	if auditEntries(t, model.AuditActionImpersonate, user) != 1 || auditEntries(t, model.AuditActionImpersonatedRequest, user) != 1 {
+		t.Error("impersonation and the write made with it were not both audited")
+	}
+
+	// The session cannot reach account routes, nor act as an admin.
+	if status := withToken(t, http.MethodDelete, response.Token, middleware.RequireSession(noContent)); status != http.StatusForbidden {
+		t.Errorf("account route: status = %d, want %d", status, http.StatusForbidden)
+	}
+	if status := withToken(t, http.MethodGet, response.Token, middleware.RequireAdmin(noContent)); status != http.StatusForbidden {
+		t.Errorf("admin route: status = %d, want %d", status, http.StatusForbidden)
+	}
+}
+
+func TestRequestEmailVerification(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+
+	requestVerification := func() int {
+		return serve(RequestEmailVerification, as(request(t, http.MethodPost, "/api/users/me/verify-email", nil), user.ID, "session")).Code
+	}
+
+	if status := requestVerification(); status != http.StatusConflict {
+		t.Errorf("verified address: status = %d, want %d", status, http.StatusConflict)
+	}
+
+	// A password account starts out unverified, with an address change pending.
+	database.DB.Model(&user).Updates(map[string]interface{}{"email_verified": false, "pending_email": "ada@new.example.com"})
+	if status := requestVerification(); status != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", status, http.StatusAccepted)
+	}
+
+	if status := verifyEmail(t, knownVerificationToken(t, user.ID)); status != http.StatusOK {
+		t.Fatalf("verify: status = %d, want %d", status, http.StatusOK)
+	}
+	var stored model.User
+	database.DB.First(&stored, user.ID)
+	if stored.Email != "ada@example.com" || !stored.EmailVerified || stored.PendingEmail != "ada@new.example.com" {
+		t.Errorf("after verification: email %q, verified %v, pending %q", stored.Email, stored.EmailVerified, stored.PendingEmail)
+	}
+}
+
+func TestVerifyEmailRejectsStaleAddress(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@new.example.com")
+
+	// A link for an address the account has since moved away from.
+	token := "stale-" + randomTestString(t)
+	create(t, &model.EmailVerification{
+		UserID:    user.ID,
+		Email:     "ada@old.example.com",
+		TokenHash: middleware.HashToken(token),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	if status := verifyEmail(t, token); status != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+	}
+	var stored model.User
+	database.DB.First(&stored, user.ID)
+	if stored.Email != "ada@new.example.com" {
+		t.Errorf("email = %q, the stale link changed it", stored.Email)
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
 )
 
@@ -209,6 +211,20 @@ Return ONLY valid JSON:`,
 		return
 	}
 
+	if userID, ok := r.Context().Value(middleware.UserIDKey).(uint); ok {
+		record := model.AISuggestionRecord{
+			UserID:          userID,
+			TaskDescription: input.TaskDescription,
+			Title:           finalSuggestion.Title,
+			Subtasks:        finalSuggestion.Subtasks,
+			Priority:        finalSuggestion.Priority,
+			TimeEstimate:    finalSuggestion.TimeEstimate,
+		}
+		if err := database.DB.Create(&record).Error; err != nil {
+			log.Printf("[AI] Could not record suggestion history: %v", err)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]interface{}{
 		"suggestions": finalSuggestion,
@@ -0,0 +1,394 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/config"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/storage"
+	"gorm.io/gorm"
+)
+
+// defaultAttachmentTypes are the content types accepted unless
+// ATTACHMENT_ALLOWED_TYPES says otherwise.
+var defaultAttachmentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+	"text/plain",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// zipDocumentTypes tells office documents apart from other ZIP archives,
+// which they are indistinguishable from by content alone.
+var zipDocumentTypes = map[string]string{
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+func attachmentMaxSize() int64 {
+	return int64(config.Int("ATTACHMENT_MAX_SIZE_MB", 10)) << 20
+}
+
+func attachmentAllowedTypes() map[string]bool {
+	types := defaultAttachmentTypes
+	if configured := splitParam(os.Getenv("ATTACHMENT_ALLOWED_TYPES")); len(configured) > 0 {
+		types = configured
+	}
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowed[strings.ToLower(t)] = true
+	}
+	return allowed
+}
+
+// attachmentContentType decides a file's type from its first bytes rather
+// than trusting what the client claims.
+func attachmentContentType(head []byte, fileName string) string {
+	contentType := http.DetectContentType(head)
+	if contentType == "application/zip" {
+		if documentType, ok := zipDocumentTypes[strings.ToLower(path.Ext(fileName))]; ok {
+			return documentType
+		}
+	}
+	return contentType
+}
+
+// attachmentFileName keeps the base name of an uploaded file without control
+// characters, so it is safe to echo back in headers.
+func attachmentFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// deleteStoredFiles removes objects whose database rows are already gone,
+// logging the ones that could not be removed.
+func deleteStoredFiles(keys []string) {
+	for _, key := range keys {
+		if err := storage.Files.Delete(context.Background(), key); err != nil {
+			log.Printf("[Storage] Could not delete %s: %v", key, err)
+		}
+	}
+}
+
+// attachmentFiles lists the stored files belonging to attachments.
+func attachmentFiles(attachments ...model.Attachment) []string {
+	var keys []string
+	for _, attachment := range attachments {
+		keys = append(keys, attachment.StorageKey)
+		if attachment.ThumbnailKey != "" {
+			keys = append(keys, attachment.ThumbnailKey)
+		}
+	}
+	return keys
+}
+
+// loadAttachment loads the attachment named in the path from the task.
+func loadAttachment(w http.ResponseWriter, r *http.Request, task *model.Task) (*model.Attachment, bool) {
+	var attachment model.Attachment
+	if err := database.DB.Where("id = ? AND task_id = ?", r.PathValue("attachmentId"), task.ID).First(&attachment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &attachment, true
+}
+
+func GetAttachments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := loadCommentTask(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var attachments []model.Attachment
+	if err := database.DB.Where("task_id = ?", task.ID).Order("created_at, id").Find(&attachments).Error; err != nil {
+		http.Error(w, "Could not retrieve attachments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"attachments": attachments})
+}
+
+// UploadAttachment stores the multipart "file" field as an attachment on the
+// task. The body is streamed to storage, so large uploads are never held in
+// memory.
+func UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := loadCommentTask(w, r, userID)
+	if !ok {
+		return
+	}
+
+	maxSize := attachmentMaxSize()
+	tooLarge := fmt.Sprintf("Attachments can be at most %d MB", maxSize>>20)
+	// Leave room for the multipart headers around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected a multipart upload", http.StatusBadRequest)
+		return
+	}
+
+	var part io.Reader
+	var fileName string
+	for {
+		p, err := reader.NextPart()
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, tooLarge, http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Missing file", http.StatusBadRequest)
+			return
+		}
+		if p.FormName() == "file" {
+			part, fileName = p, attachmentFileName(p.FileName())
+			break
+		}
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		http.Error(w, "Could not read upload", http.StatusBadRequest)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "File is empty", http.StatusBadRequest)
+		return
+	}
+	head = head[:n]
+
+	contentType := attachmentContentType(head, fileName)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !attachmentAllowedTypes()[mediaType] {
+		http.Error(w, "Files of type "+mediaType+" are not allowed", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	key, err := randomString(16)
+	if err != nil {
+		http.Error(w, "Could not store attachment", http.StatusInternalServerError)
+		return
+	}
+	key = fmt.Sprintf("tasks/%d/%s", task.ID, key)
+
+	body := &countingReader{r: io.LimitReader(io.MultiReader(bytes.NewReader(head), part), maxSize+1)}
+	if err := storage.Files.Put(r.Context(), key, body, -1, contentType); err != nil {
+		deleteStoredFiles([]string{key})
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, tooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("[Storage] Could not store %s: %v", key, err)
+		http.Error(w, "Could not store attachment", http.StatusInternalServerError)
+		return
+	}
+	if body.n > maxSize {
+		deleteStoredFiles([]string{key})
+		http.Error(w, tooLarge, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	attachment := model.Attachment{
+		TaskID:      task.ID,
+		UploadedBy:  userID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        body.n,
+		StorageKey:  key,
+	}
+	if err := database.DB.Create(&attachment).Error; err != nil {
+		deleteStoredFiles([]string{key})
+		http.Error(w, "Could not create attachment", http.StatusInternalServerError)
+		return
+	}
+	queueAttachmentProcessing()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"attachment": attachment})
+}
+
+// downloadableAttachment loads the attachment named in the path, writing an
+// error response unless it has passed its virus scan.
+func downloadableAttachment(w http.ResponseWriter, r *http.Request) (*model.Attachment, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	task, ok := loadCommentTask(w, r, userID)
+	if !ok {
+		return nil, false
+	}
+	attachment, ok := loadAttachment(w, r, task)
+	if !ok {
+		return nil, false
+	}
+
+	switch attachment.ScanStatus {
+	case model.AttachmentClean:
+		return attachment, true
+	case model.AttachmentInfected:
+		http.Error(w, "Attachment failed the virus scan", http.StatusForbidden)
+	case model.AttachmentScanFailed:
+		http.Error(w, "Attachment could not be scanned", http.StatusForbidden)
+	default:
+		w.Header().Set("Retry-After", "10")
+		http.Error(w, "Attachment is still being scanned", http.StatusConflict)
+	}
+	return nil, false
+}
+
+// serveStoredFile streams a file from storage. Images are shown inline and
+// everything else is downloaded; neither may run scripts.
+func serveStoredFile(w http.ResponseWriter, r *http.Request, key, contentType, fileName string, modified time.Time) {
+	file, err := storage.Files.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[Storage] Could not read %s: %v", key, err)
+		http.Error(w, "Could not read attachment", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(time.Hour.Seconds())))
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("[Storage] Could not send %s: %v", key, err)
+	}
+}
+
+func DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := downloadableAttachment(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	serveStoredFile(w, r, attachment.StorageKey, attachment.ContentType, attachment.FileName, *attachment.ScannedAt)
+}
+
+func DownloadAttachmentThumbnail(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := downloadableAttachment(w, r)
+	if !ok {
+		return
+	}
+	if attachment.ThumbnailKey == "" {
+		http.Error(w, "Attachment has no thumbnail", http.StatusNotFound)
+		return
+	}
+
+	serveStoredFile(w, r, attachment.ThumbnailKey, attachment.ThumbnailContentType, "thumbnail-"+attachment.FileName, *attachment.ScannedAt)
+}
+
+// DeleteAttachment removes an attachment. Its uploader and the task's creator
+// and assignee may delete it.
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := loadCommentTask(w, r, userID)
+	if !ok {
+		return
+	}
+	attachment, ok := loadAttachment(w, r, task)
+	if !ok {
+		return
+	}
+
+	if attachment.UploadedBy != userID && task.CreatedBy != userID && task.AssignedTo != userID {
+		http.Error(w, "You cannot delete this attachment", http.StatusForbidden)
+		return
+	}
+
+	if err := database.DB.Delete(attachment).Error; err != nil {
+		http.Error(w, "Could not delete attachment", http.StatusInternalServerError)
+		return
+	}
+	deleteStoredFiles(attachmentFiles(*attachment))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Attachment deleted successfully"})
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/config"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/imaging"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/scanner"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	thumbnailSize = 320
+	// A scan that has not finished in this long is assumed to have died
+	// with its worker and is started again.
+	attachmentScanTimeout = 10 * time.Minute
+	// Failed scans are retried after this long.
+	attachmentRetryDelay = time.Minute
+)
+
+// thumbnailTypes are the image types thumbnails are made for.
+var thumbnailTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// attachmentQueue wakes the worker when a file is uploaded.
+var attachmentQueue = make(chan struct{}, 1)
+
+func queueAttachmentProcessing() {
+	select {
+	case attachmentQueue <- struct{}{}:
+	default:
+	}
+}
+
+// RunAttachmentWorker processes new attachments as they are uploaded, and
+// every interval to retry failed scans.
+func RunAttachmentWorker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ProcessAttachments()
+		select {
+		case <-attachmentQueue:
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessAttachments scans every pending attachment and makes thumbnails of
+// the clean images.
+func ProcessAttachments() {
+	for {
+		attachment, err := claimAttachment()
+		if err != nil {
+			if err != gorm.ErrRecordNotFound {
+				log.Printf("[Attachments] Could not claim attachment: %v", err)
+			}
+			return
+		}
+		processAttachment(attachment)
+	}
+}
+
+// claimAttachment marks the next pending attachment as being scanned, so
+// other workers skip it.
+func claimAttachment() (model.Attachment, error) {
+	var attachment model.Attachment
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(scan_status = ? AND (scan_started_at IS NULL OR scan_started_at < ?)) OR (scan_status = ? AND scan_started_at < ?)",
+				model.AttachmentPending, time.Now().Add(-attachmentRetryDelay),
+				model.AttachmentScanning, time.Now().Add(-attachmentScanTimeout)).
+			Order("id").
+			First(&attachment).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		attachment.ScanStatus = model.AttachmentScanning
+		attachment.ScanStartedAt = &now
+		attachment.ScanAttempts++
+		return tx.Select("ScanStatus", "ScanStartedAt", "ScanAttempts").Save(&attachment).Error
+	})
+	return attachment, err
+}
+
+func processAttachment(attachment model.Attachment) {
+	ctx, cancel := context.WithTimeout(context.Background(), attachmentScanTimeout)
+	defer cancel()
+
+	// Images are needed in memory for thumbnails anyway; everything else is
+	// streamed to the scanner.
+	mediaType, _, _ := strings.Cut(attachment.ContentType, ";")
+	image := thumbnailTypes[mediaType]
+
+	file, err := storage.Files.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		scanFailed(attachment, err)
+		return
+	}
+	var data []byte
+	var content io.Reader = file
+	if image {
+		data, err = io.ReadAll(file)
+		content = bytes.NewReader(data)
+	}
+	var result scanner.Result
+	if err == nil {
+		result, err = scanner.Default.Scan(ctx, content)
+	}
+	file.Close()
+	if err != nil {
+		scanFailed(attachment, err)
+		return
+	}
+
+	now := time.Now()
+	attachment.ScannedAt = &now
+	if !result.Clean {
+		attachment.ScanStatus = model.AttachmentInfected
+		attachment.ScanResult = result.Signature
+		log.Printf("[Attachments] Attachment %d is infected: %s", attachment.ID, result.Signature)
+		saveProcessedAttachment(attachment, nil)
+		return
+	}
+	attachment.ScanStatus = model.AttachmentClean
+
+	var written []string
+	if image {
+		written = prepareImage(ctx, &attachment, data)
+	}
+	saveProcessedAttachment(attachment, written)
+}
+
+// prepareImage strips the image's metadata and stores a thumbnail of it,
+// returning the keys it wrote. A failure only costs the attachment its
+// thumbnail.
+func prepareImage(ctx context.Context, attachment *model.Attachment, data []byte) []string {
+	var written []string
+
+	stripped, changed, err := imaging.StripMetadata(data, attachment.ContentType)
+	if err != nil {
+		log.Printf("[Attachments] Could not strip metadata from attachment %d: %v", attachment.ID, err)
+	} else if changed {
+		if err := storage.Files.Put(ctx, attachment.StorageKey, bytes.NewReader(stripped), int64(len(stripped)), attachment.ContentType); err != nil {
+			log.Printf("[Attachments] Could not store attachment %d without metadata: %v", attachment.ID, err)
+		} else {
+			attachment.Size = int64(len(stripped))
+			written = append(written, attachment.StorageKey)
+		}
+	}
+
+	thumbnail, contentType, err := imaging.Thumbnail(data, thumbnailSize)
+	if err != nil {
+		log.Printf("[Attachments] Could not make a thumbnail of attachment %d: %v", attachment.ID, err)
+		return written
+	}
+	key := attachment.StorageKey + "-thumbnail"
+	if err := storage.Files.Put(ctx, key, bytes.NewReader(thumbnail), int64(len(thumbnail)), contentType); err != nil {
+		log.Printf("[Attachments] Could not store thumbnail of attachment %d: %v", attachment.ID, err)
+		return written
+	}
+	attachment.ThumbnailKey = key
+	attachment.ThumbnailContentType = contentType
+	return append(written, key)
+}
+
+// saveProcessedAttachment records the outcome. If the attachment was deleted
+// meanwhile, the files written for it are removed again.
+func saveProcessedAttachment(attachment model.Attachment, written []string) {
+	result := database.DB.Model(&attachment).
+		Where("scan_status = ?", model.AttachmentScanning).
+		Select("ScanStatus", "ScanResult", "ScannedAt", "Size", "ThumbnailKey", "ThumbnailContentType").
+		Updates(&attachment)
+	if result.Error != nil {
+		log.Printf("[Attachments] Could not save attachment %d: %v", attachment.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		deleteStoredFiles(written)
+	}
+}
+
+// scanFailed puts the attachment back in the queue, or gives up on it after
+// ATTACHMENT_SCAN_ATTEMPTS tries.
+func scanFailed(attachment model.Attachment, err error) {
+	log.Printf("[Attachments] Could not scan attachment %d: %v", attachment.ID, err)
+
+	attachment.ScanStatus = model.AttachmentPending
+	if attachment.ScanAttempts >= config.Int("ATTACHMENT_SCAN_ATTEMPTS", 5) {
+		now := time.Now()
+		attachment.ScanStatus = model.AttachmentScanFailed
+		attachment.ScanResult = "scan failed"
+		attachment.ScannedAt = &now
+	}
+	saveProcessedAttachment(attachment, nil)
+}
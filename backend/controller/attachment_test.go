@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/storage"
+)
+
+var (
+	pngHeader = []byte("\x89PNG\r\n\x1a\n")
+	zipHeader = []byte("PK\x03\x04")
+)
+
+// useTestStorage stores attachments in a temporary directory until the test
+// ends.
+func useTestStorage(t *testing.T) {
+	t.Helper()
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := storage.Files
+	storage.Files = store
+	t.Cleanup(func() { storage.Files = previous })
+}
+
+// upload sends contents as the "file" field of a multipart upload.
+func upload(t *testing.T, user model.User, task model.Task, fileName string, contents []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(contents)
+	form.Close()
+
+	id := strconv.FormatUint(uint64(task.ID), 10)
+	r := as(httptest.NewRequest(http.MethodPost, "/api/tasks/"+id+"/attachments", &body), user.ID, "session")
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r.SetPathValue("id", id)
+	return serve(UploadAttachment, r)
+}
+
+// uploadAttachment uploads a file that must be accepted.
+func uploadAttachment(t *testing.T, user model.User, task model.Task, fileName string, contents []byte) model.Attachment {
+	t.Helper()
+	rec := upload(t, user, task, fileName, contents)
+	expect(t, rec, http.StatusCreated)
+	var response struct {
+		Attachment model.Attachment `json:"attachment"`
+	}
+	decode(t, rec, &response)
+	return response.Attachment
+}
+
+// attachmentRequest builds a request as user for the attachment named in the
+// path.
+func attachmentRequest(t *testing.T, method string, user model.User, attachment model.Attachment) *http.Request {
+	t.Helper()
+	r := taskRequest(t, method, user, attachment.TaskID, nil)
+	r.SetPathValue("attachmentId", strconv.FormatUint(uint64(attachment.ID), 10))
+	return r
+}
+
+// storedFile reads an object back from storage.
+func storedFile(t *testing.T, key string) ([]byte, error) {
+	t.Helper()
+	file, err := storage.Files.Get(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func TestUploadAttachment(t *testing.T) {
+	dbtest.Open(t)
+	useTestStorage(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	stranger := newUser(t, "Eve", "eve@example.com")
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report"})
+
+	contents := append(append([]byte{}, pngHeader...), "rest of the image"...)
+	expect(t, upload(t, stranger, task, "chart.png", contents), http.StatusNotFound)
+
+	attachment := uploadAttachment(t, owner, task, "../../chart.png", contents)
+	if attachment.FileName != "chart.png" || attachment.ContentType != "image/png" || attachment.Size != int64(len(contents)) {
+		t.Errorf("attachment = %+v, want chart.png as image/png of %d bytes", attachment, len(contents))
+	}
+	if attachment.ScanStatus != model.AttachmentPending {
+		t.Errorf("scan status = %q, want %q", attachment.ScanStatus, model.AttachmentPending)
+	}
+
+	var stored model.Attachment
+	database.DB.First(&stored, attachment.ID)
+	if data, err := storedFile(t, stored.StorageKey); err != nil || !bytes.Equal(data, contents) {
+		t.Errorf("stored file = %q, %v, want the upload", data, err)
+	}
+}
+
+func TestUploadAttachmentTooLarge(t *testing.T) {
+	dbtest.Open(t)
+	useTestStorage(t)
+	t.Setenv("ATTACHMENT_MAX_SIZE_MB", "1")
+	owner := newUser(t, "Ada", "ada@example.com")
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report"})
+
+	exact := append([]byte("plain text "), bytes.Repeat([]byte("a"), 1<<20-11)...)
+	uploadAttachment(t, owner, task, "notes.txt", exact)
+
+	expect(t, upload(t, owner, task, "notes.txt", append(exact, 'a')), http.StatusRequestEntityTooLarge)
+	expect(t, upload(t, owner, task, "notes.txt", append(exact, bytes.Repeat([]byte("a"), 2<<20)...)), http.StatusRequestEntityTooLarge)
+
+	var count int64
+	database.DB.Model(&model.Attachment{}).Where("task_id = ?", task.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("%d attachments stored, want only the one within the limit", count)
+	}
+}
+
+func TestUploadAttachmentChecksContent(t *testing.T) {
+	dbtest.Open(t)
+	useTestStorage(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report"})
+
+	// The claimed name does not make a script an image.
+	expect(t, upload(t, owner, task, "chart.png", []byte("<html><script>alert(1)</script></html>")), http.StatusUnsupportedMediaType)
+	expect(t, upload(t, owner, task, "empty.txt", nil), http.StatusBadRequest)
+
+	// ZIP archives are only accepted as the office documents they claim to be.
+	archive := append(append([]byte{}, zipHeader...), "archive contents"...)
+	expect(t, upload(t, owner, task, "files.zip", archive), http.StatusUnsupportedMediaType)
+	document := uploadAttachment(t, owner, task, "Report.DOCX", archive)
+	if want := zipDocumentTypes[".docx"]; document.ContentType != want {
+		t.Errorf("content type = %q, want %q", document.ContentType, want)
+	}
+
+	t.Setenv("ATTACHMENT_ALLOWED_TYPES", "image/png")
+	expect(t, upload(t, owner, task, "notes.txt", []byte("plain text")), http.StatusUnsupportedMediaType)
+	uploadAttachment(t, owner, task, "chart.png", pngHeader)
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	dbtest.Open(t)
+	useTestStorage(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	uploader := newUser(t, "Bob", "bob@example.com")
+	follower := newUser(t, "Cy", "cy@example.com")
+	stranger := newUser(t, "Eve", "eve@example.com")
+	workspace := newWorkspace(t, owner)
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report"})
+	for _, user := range []model.User{uploader, follower} {
+		addMember(t, workspace, user, model.WorkspaceRoleMember)
+		if status := watch(t, owner, task, user); status != http.StatusOK {
+			t.Fatalf("adding a watcher: status = %d, want %d", status, http.StatusOK)
+		}
+	}
+
+	attachment := uploadAttachment(t, uploader, task, "notes.txt", []byte("plain text"))
+	var stored model.Attachment
+	database.DB.First(&stored, attachment.ID)
+
+	expect(t, serve(DeleteAttachment, attachmentRequest(t, http.MethodDelete, stranger, attachment)), http.StatusNotFound)
+	expect(t, serve(DeleteAttachment, attachmentRequest(t, http.MethodDelete, follower, attachment)), http.StatusForbidden)
+	expect(t, serve(DeleteAttachment, attachmentRequest(t, http.MethodDelete, uploader, attachment)), http.StatusOK)
+	if _, err := storedFile(t, stored.StorageKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("file of the deleted attachment: error = %v, want ErrNotFound", err)
+	}
+
+	// The task's creator may delete anyone's attachment.
+	attachment = uploadAttachment(t, uploader, task, "notes.txt", []byte("plain text"))
+	expect(t, serve(DeleteAttachment, attachmentRequest(t, http.MethodDelete, owner, attachment)), http.StatusOK)
+	expect(t, serve(DeleteAttachment, attachmentRequest(t, http.MethodDelete, owner, attachment)), http.StatusNotFound)
+}
+
+func TestPurgeTrashedTasksDeletesAttachmentFiles(t *testing.T) {
+	dbtest.Open(t)
+	useTestStorage(t)
+	t.Setenv("TASK_TRASH_RETENTION", "720h")
+	owner := newUser(t, "Ada", "ada@example.com")
+	expired := newTask(t, owner, map[string]interface{}{"title": "Old"})
+	kept := newTask(t, owner, map[string]interface{}{"title": "Live"})
+
+	keys := make(map[uint]string)
+	for _, task := range []model.Task{expired, kept} {
+		attachment := uploadAttachment(t, owner, task, "notes.txt", []byte("plain text"))
+		thumbnail := "tasks/" + strconv.FormatUint(uint64(task.ID), 10) + "/thumbnail"
+		storage.Files.Put(context.Background(), thumbnail, strings.NewReader("thumbnail"), -1, "image/png")
+		database.DB.Model(&model.Attachment{}).Where("id = ?", attachment.ID).Update("thumbnail_key", thumbnail)
+		var stored model.Attachment
+		database.DB.First(&stored, attachment.ID)
+		keys[task.ID] = stored.StorageKey
+	}
+	database.DB.Model(&model.Task{}).Where("id = ?", expired.ID).Update("deleted_at", time.Now().AddDate(0, 0, -31))
+
+	PurgeTrashedTasks()
+
+	for _, key := range []string{keys[expired.ID], "tasks/" + strconv.FormatUint(uint64(expired.ID), 10) + "/thumbnail"} {
+		if _, err := storedFile(t, key); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s of the purged task: error = %v, want ErrNotFound", key, err)
+		}
+	}
+	if _, err := storedFile(t, keys[kept.ID]); err != nil {
+		t.Errorf("file of a live task was removed: %v", err)
+	}
+	var count int64
+	database.DB.Model(&model.Attachment{}).Where("task_id = ?", expired.ID).Count(&count)
+	if count != 0 {
+		t.Error("attachments of the purged task remain")
+	}
+}
+
+func TestDownloadAttachmentWaitsForScan(t *testing.T) {
+	dbtest.Open(t)
+	useTestStorage(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report"})
+	attachment := uploadAttachment(t, owner, task, "notes.txt", []byte("plain text"))
+
+	tests := []struct {
+		status string
+		want   int
+	}{
+		{model.AttachmentPending, http.StatusConflict},
+		{model.AttachmentScanning, http.StatusConflict},
+		{model.AttachmentInfected, http.StatusForbidden},
+		{model.AttachmentScanFailed, http.StatusForbidden},
+		{model.AttachmentClean, http.StatusOK},
+	}
+	for _, tt := range tests {
+		database.DB.Model(&model.Attachment{}).Where("id = ?", attachment.ID).
+			Updates(map[string]interface{}{"scan_status": tt.status, "scanned_at": time.Now()})
+
+		rec := serve(DownloadAttachment, attachmentRequest(t, http.MethodGet, owner, attachment))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.status, rec.Code, tt.want)
+			continue
+		}
+		if tt.want == http.StatusOK && rec.Body.String() != "plain text" {
+			t.Errorf("%s: body = %q, want the upload", tt.status, rec.Body.String())
+		}
+		if tt.want != http.StatusOK && rec.Body.String() == "plain text" {
+			t.Errorf("%s: the file was sent", tt.status)
+		}
+	}
+
+	// Thumbnails are held back the same way.
+	database.DB.Model(&model.Attachment{}).Where("id = ?", attachment.ID).Update("scan_status", model.AttachmentInfected)
+	expect(t, serve(DownloadAttachmentThumbnail, attachmentRequest(t, http.MethodGet, owner, attachment)), http.StatusForbidden)
+}
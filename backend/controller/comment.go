@@ -0,0 +1,304 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/markdown"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/websocket"
+	"gorm.io/gorm"
+)
+
+// loadCommentTask loads the task named in the path if the user can see it,
+// writing an error response otherwise.
+func loadCommentTask(w http.ResponseWriter, r *http.Request, userID uint) (*model.Task, bool) {
+	var task model.Task
+	if err := database.DB.Scopes(visibleTasks(userID)).Where("tasks.id = ?", r.PathValue("id")).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &task, true
+}
+
+// loadOwnComment loads the comment named in the path, writing an error
+// response unless it is on the task and was written by the user.
+func loadOwnComment(w http.ResponseWriter, r *http.Request, task *model.Task, userID uint) (*model.Comment, bool) {
+	var comment model.Comment
+	if err := database.DB.Where("id = ? AND task_id = ?", r.PathValue("commentId"), task.ID).First(&comment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if comment.AuthorID != userID {
+		http.Error(w, "You can only change your own comments", http.StatusForbidden)
+		return nil, false
+	}
+	return &comment, true
+}
+
+// commentBody validates a comment's Markdown and renders it.
+func commentBody(body string) (string, string, bool) {
+	body = strings.TrimSpace(body)
+	if body == "" || len(body) > model.MaxCommentLength {
+		return "", "", false
+	}
+	html, err := markdown.Render(body)
+	if err != nil {
+		return "", "", false
+	}
+	return body, html, true
+}
+
+type commentNode struct {
+	model.Comment
+	Replies []*commentNode `json:"replies"`
+}
+
+// GetComments returns a task's comments as threads, oldest first. Deleted
+// comments that have replies are kept as empty placeholders.
+func GetComments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := loadCommentTask(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var comments []model.Comment
+	if err := database.DB.Unscoped().Where("task_id = ?", task.ID).Order("created_at, id").Find(&comments).Error; err != nil {
+		http.Error(w, "Could not retrieve comments", http.StatusInternalServerError)
+		return
+	}
+
+	nodes := make(map[uint]*commentNode, len(comments))
+	for _, comment := range comments {
+		if comment.DeletedAt.Valid {
+			comment.Body = ""
+			comment.BodyHTML = ""
+		}
+		nodes[comment.ID] = &commentNode{Comment: comment, Replies: []*commentNode{}}
+	}
+
+	threads := []*commentNode{}
+	for _, comment := range comments {
+		node := nodes[comment.ID]
+		if parent, ok := nodes[derefID(comment.ParentID)]; ok {
+			parent.Replies = append(parent.Replies, node)
+		} else {
+			threads = append(threads, node)
+		}
+	}
+	threads = pruneDeleted(threads)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"comments": threads})
+}
+
+func derefID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// pruneDeleted drops deleted comments that have no remaining replies.
+func pruneDeleted(nodes []*commentNode) []*commentNode {
+	kept := nodes[:0]
+	for _, node := range nodes {
+		node.Replies = pruneDeleted(node.Replies)
+		if node.DeletedAt.Valid && len(node.Replies) == 0 {
+			continue
+		}
+		kept = append(kept, node)
+	}
+	return kept
+}
+
+func CreateComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := loadCommentTask(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var input model.CommentInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	body, html, ok := commentBody(input.Body)
+	if !ok {
+		http.Error(w, "Comment must be between 1 and 10000 characters", http.StatusBadRequest)
+		return
+	}
+
+	if input.ParentID != nil {
+		var count int64
+		database.DB.Model(&model.Comment{}).Where("id = ? AND task_id = ?", *input.ParentID, task.ID).Count(&count)
+		if count == 0 {
+			http.Error(w, "Parent comment not found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	comment := model.Comment{
+		TaskID:   task.ID,
+		ParentID: input.ParentID,
+		AuthorID: userID,
+		Body:     body,
+		BodyHTML: html,
+	}
+	if err := database.DB.Create(&comment).Error; err != nil {
+		http.Error(w, "Could not create comment", http.StatusInternalServerError)
+		return
+	}
+
+	autoWatch(task.ID, userID)
+	websocket.BroadcastCommentUpdate(comment, "created", taskWatchers(task))
+	mentioned := recordMentions(task, userID, "", comment.Body, &comment.ID)
+	notifyComment(task, &comment, mentioned)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"comment": comment})
+}
+
+// UpdateComment changes a comment's body and keeps the previous one in its
+// edit history.
+func UpdateComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := loadCommentTask(w, r, userID)
+	if !ok {
+		return
+	}
+	comment, ok := loadOwnComment(w, r, task, userID)
+	if !ok {
+		return
+	}
+
+	var input model.CommentUpdateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	body, html, ok := commentBody(input.Body)
+	if !ok {
+		http.Error(w, "Comment must be between 1 and 10000 characters", http.StatusBadRequest)
+		return
+	}
+
+	if body != comment.Body {
+		previous := comment.Body
+		now := time.Now()
+		comment.Body = body
+		comment.BodyHTML = html
+		comment.EditedAt = &now
+
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&model.CommentEdit{CommentID: comment.ID, Body: previous, EditedBy: userID}).Error; err != nil {
+				return err
+			}
+			return tx.Select("Body", "BodyHTML", "EditedAt", "UpdatedAt").Save(comment).Error
+		})
+		if err != nil {
+			http.Error(w, "Could not update comment", http.StatusInternalServerError)
+			return
+		}
+
+		websocket.BroadcastCommentUpdate(*comment, "updated", taskWatchers(task))
+		recordMentions(task, userID, previous, comment.Body, &comment.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"comment": comment})
+}
+
+func GetCommentEdits(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := loadCommentTask(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var comment model.Comment
+	if err := database.DB.Where("id = ? AND task_id = ?", r.PathValue("commentId"), task.ID).First(&comment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var edits []model.CommentEdit
+	if err := database.DB.Where("comment_id = ?", comment.ID).Order("created_at DESC, id DESC").Find(&edits).Error; err != nil {
+		http.Error(w, "Could not retrieve comment history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"edits": edits})
+}
+
+func DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := loadCommentTask(w, r, userID)
+	if !ok {
+		return
+	}
+	comment, ok := loadOwnComment(w, r, task, userID)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(comment).Error; err != nil {
+		http.Error(w, "Could not delete comment", http.StatusInternalServerError)
+		return
+	}
+
+	deleted := *comment
+	deleted.Body = ""
+	deleted.BodyHTML = ""
+	websocket.BroadcastCommentUpdate(deleted, "deleted", taskWatchers(task))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Comment deleted successfully"})
+}
@@ -3,6 +3,8 @@ package controller
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
@@ -17,13 +19,18 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	input.Email = strings.TrimSpace(input.Email)
 	if input.Name == "" || input.Email == "" || input.Password == "" {
 		http.Error(w, "Name, email and password are required", http.StatusBadRequest)
 		return
 	}
+	if !validName(input.Name) {
+		http.Error(w, "Name cannot contain control characters", http.StatusBadRequest)
+		return
+	}
 
 	var existingUser model.User
-	if err := database.DB.Where("email = ?", input.Email).First(&existingUser).Error; err == nil {
+	if err := database.DB.Where("LOWER(email) = LOWER(?)", input.Email).First(&existingUser).Error; err == nil {
 		http.Error(w, "Email already in use", http.StatusConflict)
 		return
 	}
@@ -35,9 +42,10 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := model.User{
-		Name:     input.Name,
-		Email:    input.Email,
-		Password: hashedPassword,
+		Name:        input.Name,
+		Email:       input.Email,
+		Password:    hashedPassword,
+		Preferences: model.DefaultPreferences(),
 	}
 
 	if err := database.DB.Create(&user).Error; err != nil {
@@ -45,24 +53,13 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := middleware.GenerateToken(user.ID)
+	token, err := startSession(r, user.ID)
 	if err != nil {
 		http.Error(w, "Could not generate token", http.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]interface{}{
-		"user": map[string]interface{}{
-			"id":    user.ID,
-			"name":  user.Name,
-			"email": user.Email,
-		},
-		"token": token,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	writeAuthResponse(w, http.StatusCreated, user, token, acceptInvitationOnAuth(&user, input.InviteToken))
 }
 
 func LoginUser(w http.ResponseWriter, r *http.Request) {
@@ -72,9 +69,23 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	policy := loadLockoutPolicy()
+
+	blocked, err := ipBlocked(policy, middleware.ClientIP(r))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if blocked {
+		recordLoginAttempt(r, nil, input.Email, model.LoginOutcomeIPBlocked)
+		writeTooManyAttempts(w, "Too many failed login attempts, try again later", policy.IPWindow)
+		return
+	}
+
 	var user model.User
-	if err := database.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+	if err := database.DB.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(input.Email)).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
+			recordLoginAttempt(r, nil, input.Email, model.LoginOutcomeUnknownUser)
 			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 			return
 		}
@@ -82,17 +93,54 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A locked account answers like a wrong password, so the lockout does not
+	// reveal that the account exists. Its owner learns of it by email.
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		recordLoginAttempt(r, &user.ID, input.Email, model.LoginOutcomeAccountLocked)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
 	if !middleware.CheckPasswordHash(input.Password, user.Password) {
+		locked, err := registerFailedLogin(policy, &user)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		outcome := model.LoginOutcomeInvalidPassword
+		if locked {
+			outcome = model.LoginOutcomeLockedOut
+		}
+		recordLoginAttempt(r, &user.ID, input.Email, outcome)
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	token, err := middleware.GenerateToken(user.ID)
+	if user.DisabledAt != nil {
+		recordLoginAttempt(r, &user.ID, input.Email, model.LoginOutcomeAccountDisabled)
+		http.Error(w, "Account disabled", http.StatusForbidden)
+		return
+	}
+	if user.PasswordResetRequired {
+		recordLoginAttempt(r, &user.ID, input.Email, model.LoginOutcomePasswordResetRequired)
+		http.Error(w, "Password reset required, check your email for a reset link", http.StatusForbidden)
+		return
+	}
+
+	resetFailedLogins(&user)
+	recordLoginAttempt(r, &user.ID, input.Email, model.LoginOutcomeSuccess)
+
+	token, err := startSession(r, user.ID)
 	if err != nil {
 		http.Error(w, "Could not generate token", http.StatusInternalServerError)
 		return
 	}
 
+	writeAuthResponse(w, http.StatusOK, user, token, acceptInvitationOnAuth(&user, input.InviteToken))
+}
+
+// writeAuthResponse writes the user and token, plus any extra top-level fields.
+func writeAuthResponse(w http.ResponseWriter, status int, user model.User, token string, extra map[string]interface{}) {
 	response := map[string]interface{}{
 		"user": map[string]interface{}{
 			"id":    user.ID,
@@ -101,7 +149,11 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 		},
 		"token": token,
 	}
+	for key, value := range extra {
+		response[key] = value
+	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(response)
 }
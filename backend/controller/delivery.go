@@ -0,0 +1,291 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/config"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/mailer"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/websocket"
+)
+
+// notify routes a notification according to its user's preferences: it is
+// put in the inbox and pushed, emailed, posted to their webhook or dropped.
+// During quiet hours it only goes to the inbox. Failures are logged rather
+// than returned so they never fail the request that triggered them.
+func notify(notification model.Notification) {
+	var user model.User
+	if err := database.DB.Select("id", "name", "email", "preferences").First(&user, notification.UserID).Error; err != nil {
+		log.Printf("Could not notify user %d of %s: %v", notification.UserID, notification.Type, err)
+		return
+	}
+
+	channel := notificationChannel(user.Preferences, notification.Type)
+	if channel == model.ChannelNone {
+		return
+	}
+	quiet := inQuietHours(user.Preferences.Notifications.QuietHours, time.Now())
+
+	if channel == model.ChannelInApp || quiet {
+		if err := database.DB.Create(&notification).Error; err != nil {
+			log.Printf("Could not notify user %d of %s: %v", notification.UserID, notification.Type, err)
+			return
+		}
+		if !quiet {
+			websocket.BroadcastNotification(notification)
+		}
+		return
+	}
+
+	notification.CreatedAt = time.Now()
+	switch channel {
+	case model.ChannelEmail:
+		body := notification.Message
+		if notification.TaskID != nil {
+			body += "\n\n" + appURL(fmt.Sprintf("/dashboard/task/%d", *notification.TaskID))
+		}
+		mailer.SendAsync(user.Email, notificationSubject(notification.Type), fmt.Sprintf("Hi %s,\n\n%s", user.Name, body))
+	case model.ChannelWebhook:
+		sendWebhookAsync(user.Preferences.Notifications.WebhookURL, notification)
+	}
+}
+
+// notificationSubjects are the email subjects for each event type. Messages
+// quote other users' names and task titles, so they are kept to the body.
+var notificationSubjects = map[string]string{
+	model.NotificationAssigned:      "A task was assigned to you",
+	model.NotificationStatusChanged: "A task you follow changed status",
+	model.NotificationDueSoon:       "A task is due soon",
+	model.NotificationMention:       "You were mentioned in a task",
+	model.NotificationComment:       "New comment on a task you follow",
+}
+
+func notificationSubject(eventType string) string {
+	if subject, ok := notificationSubjects[eventType]; ok {
+		return subject
+	}
+	return "New notification"
+}
+
+// notificationChannel picks the channel for an event type. Types without a
+// choice go in-app, and the older on/off switches still turn a channel off.
+func notificationChannel(prefs model.UserPreferences, eventType string) string {
+	channel := prefs.Notifications.Channels[eventType]
+	if channel == "" {
+		channel = model.ChannelInApp
+	}
+	switch {
+	case channel == model.ChannelInApp && !prefs.InAppNotifications,
+		channel == model.ChannelEmail && !prefs.EmailNotifications,
+		channel == model.ChannelWebhook && prefs.Notifications.WebhookURL == "":
+		return model.ChannelNone
+	}
+	return channel
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(value string) (int, bool) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	if !ok || len(hours) != 2 || len(minutes) != 2 {
+		return 0, false
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return 0, false
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// inQuietHours reports whether now falls in the quiet window, which may wrap
+// past midnight. Invalid settings never silence anything.
+func inQuietHours(quiet *model.QuietHours, now time.Time) bool {
+	if quiet == nil {
+		return false
+	}
+	start, ok := parseClock(quiet.Start)
+	if !ok {
+		return false
+	}
+	end, ok := parseClock(quiet.End)
+	if !ok || start == end {
+		return false
+	}
+	location, err := time.LoadLocation(quiet.TimeZone)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// validateNotificationSettings checks settings before they are saved.
+func validateNotificationSettings(settings model.NotificationSettings) string {
+	for eventType, channel := range settings.Channels {
+		known := false
+		for _, t := range model.NotificationTypes {
+			known = known || t == eventType
+		}
+		if !known {
+			return "Unknown notification type: " + eventType
+		}
+		switch channel {
+		case model.ChannelInApp, model.ChannelEmail, model.ChannelWebhook, model.ChannelNone:
+		default:
+			return "Invalid channel for " + eventType + ": " + channel
+		}
+		if channel == model.ChannelWebhook && settings.WebhookURL == "" {
+			return "A webhook URL is required to deliver " + eventType + " by webhook"
+		}
+	}
+
+	if settings.WebhookURL != "" {
+		u, err := url.Parse(settings.WebhookURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return "Webhook URL must be an http or https URL"
+		}
+	}
+
+	if quiet := settings.QuietHours; quiet != nil {
+		if _, ok := parseClock(quiet.Start); !ok {
+			return "Quiet hours start must be HH:MM"
+		}
+		if _, ok := parseClock(quiet.End); !ok {
+			return "Quiet hours end must be HH:MM"
+		}
+		if quiet.Start == quiet.End {
+			return "Quiet hours must not start and end at the same time"
+		}
+		if quiet.TimeZone == "" {
+			return "Quiet hours need a time zone"
+		}
+		if _, err := time.LoadLocation(quiet.TimeZone); err != nil {
+			return "Unknown time zone: " + quiet.TimeZone
+		}
+	}
+	return ""
+}
+
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// specialPurposePrefixes are the ranges from the IANA special-purpose address
+// registries that are global unicast on paper but not the public internet:
+// shared carrier NAT, benchmarking, documentation, and translation prefixes
+// that can embed an internal IPv4 address.
+var specialPurposePrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("3fff::/20"),
+	netip.MustParsePrefix("5f00::/16"),
+}
+
+// publicAddress reports whether addr is a global unicast address outside the
+// private and special-purpose ranges.
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range specialPurposePrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// webhookClient refuses to connect to addresses that are not public, so a
+// webhook URL cannot be used to reach internal services, unless
+// NOTIFY_WEBHOOK_ALLOW_PRIVATE is set for local development.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				if config.Bool("NOTIFY_WEBHOOK_ALLOW_PRIVATE", false) {
+					return nil
+				}
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip, err := netip.ParseAddr(host)
+				if err != nil || !publicAddress(ip) {
+					return errPrivateAddress
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// sendWebhookAsync posts the notification as JSON to the user's webhook in
+// the background.
+func sendWebhookAsync(target string, notification model.Notification) {
+	go func() {
+		payload, err := json.Marshal(map[string]interface{}{"notification": notification})
+		if err != nil {
+			log.Printf("[Webhook] Could not encode notification for user %d: %v", notification.UserID, err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+		if err != nil {
+			log.Printf("[Webhook] Invalid webhook for user %d: %v", notification.UserID, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := webhookClient.Do(req)
+		if err != nil {
+			log.Printf("[Webhook] Delivery to user %d failed: %v", notification.UserID, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("[Webhook] Delivery to user %d failed with status %d", notification.UserID, resp.StatusCode)
+		}
+	}()
+}
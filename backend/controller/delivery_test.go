@@ -0,0 +1,349 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		value   string
+		minutes int
+		ok      bool
+	}{
+		{"00:00", 0, true},
+		{"07:30", 450, true},
+		{"23:59", 1439, true},
+		{"24:00", 0, false},
+		{"12:60", 0, false},
+		{"7:30", 0, false},
+		{"07:3", 0, false},
+		{"0730", 0, false},
+		{"-1:30", 0, false},
+		{"ab:cd", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		minutes, ok := parseClock(tt.value)
+		if minutes != tt.minutes || ok != tt.ok {
+			t.Errorf("parseClock(%q) = %d, %t, want %d, %t", tt.value, minutes, ok, tt.minutes, tt.ok)
+		}
+	}
+}
+
+func TestInQuietHours(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 6, 1, hour, minute, 0, 0, time.UTC)
+	}
+	overnight := &model.QuietHours{Start: "22:00", End: "07:00", TimeZone: "UTC"}
+	daytime := &model.QuietHours{Start: "09:00", End: "17:30", TimeZone: "UTC"}
+	// Berlin is UTC+2 in June.
+	berlin := &model.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Europe/Berlin"}
+
+	tests := []struct {
+		name  string
+		quiet *model.QuietHours
+		now   time.Time
+		want  bool
+	}{
+		{"no quiet hours", nil, at(23, 0), false},
+		{"overnight before start", overnight, at(21, 59), false},
+		{"overnight at start", overnight, at(22, 0), true},
+		{"overnight after midnight", overnight, at(3, 0), true},
+		{"overnight at end", overnight, at(7, 0), false},
+		{"daytime inside", daytime, at(12, 0), true},
+		{"daytime before", daytime, at(8, 59), false},
+		{"daytime at end", daytime, at(17, 30), false},
+		{"time zone applied", berlin, at(20, 30), true},
+		{"time zone morning", berlin, at(5, 30), false},
+		{"unknown time zone", &model.QuietHours{Start: "00:00", End: "23:59", TimeZone: "Mars/Base"}, at(12, 0), false},
+		{"invalid start", &model.QuietHours{Start: "late", End: "07:00", TimeZone: "UTC"}, at(23, 0), false},
+		{"empty window", &model.QuietHours{Start: "08:00", End: "08:00", TimeZone: "UTC"}, at(8, 0), false},
+	}
+	for _, tt := range tests {
+		if got := inQuietHours(tt.quiet, tt.now); got != tt.want {
+			t.Errorf("%s: inQuietHours at %s = %t, want %t", tt.name, tt.now.Format("15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestNotificationChannel(t *testing.T) {
+	prefs := model.DefaultPreferences()
+	prefs.Notifications.Channels = map[string]string{
+		model.NotificationComment:  model.ChannelEmail,
+		model.NotificationDueSoon:  model.ChannelWebhook,
+		model.NotificationMention:  model.ChannelNone,
+		model.NotificationAssigned: model.ChannelInApp,
+	}
+
+	tests := []struct {
+		name  string
+		prefs func(*model.UserPreferences)
+		event string
+		want  string
+	}{
+		{"unset defaults to in-app", nil, model.NotificationStatusChanged, model.ChannelInApp},
+		{"chosen channel", nil, model.NotificationComment, model.ChannelEmail},
+		{"turned off", nil, model.NotificationMention, model.ChannelNone},
+		{"webhook without URL", nil, model.NotificationDueSoon, model.ChannelNone},
+		{
+			"webhook with URL",
+			func(p *model.UserPreferences) { p.Notifications.WebhookURL = "https://example.com/hook" },
+			model.NotificationDueSoon, model.ChannelWebhook,
+		},
+		{
+			"email switched off",
+			func(p *model.UserPreferences) { p.EmailNotifications = false },
+			model.NotificationComment, model.ChannelNone,
+		},
+		{
+			"in-app switched off",
+			func(p *model.UserPreferences) { p.InAppNotifications = false },
+			model.NotificationAssigned, model.ChannelNone,
+		},
+	}
+	for _, tt := range tests {
+		p := prefs
+		if tt.prefs != nil {
+			tt.prefs(&p)
+		}
+		if got := notificationChannel(p, tt.event); got != tt.want {
+			t.Errorf("%s: notificationChannel(%s) = %s, want %s", tt.name, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestValidateNotificationSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings model.NotificationSettings
+		want     string
+	}{
+		{"empty", model.NotificationSettings{}, ""},
+		{
+			"valid",
+			model.NotificationSettings{
+				Channels:   map[string]string{model.NotificationComment: model.ChannelWebhook},
+				WebhookURL: "https://example.com/hook",
+				QuietHours: &model.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Europe/Berlin"},
+			},
+			"",
+		},
+		{
+			"unknown type",
+			model.NotificationSettings{Channels: map[string]string{"birthday": model.ChannelEmail}},
+			"Unknown notification type: birthday",
+		},
+		{
+			"unknown channel",
+			model.NotificationSettings{Channels: map[string]string{model.NotificationComment: "sms"}},
+			"Invalid channel for comment: sms",
+		},
+		{
+			"webhook without URL",
+			model.NotificationSettings{Channels: map[string]string{model.NotificationComment: model.ChannelWebhook}},
+			"A webhook URL is required to deliver comment by webhook",
+		},
+		{"non-http webhook", model.NotificationSettings{WebhookURL: "ftp://example.com"}, "Webhook URL must be an http or https URL"},
+		{"webhook without host", model.NotificationSettings{WebhookURL: "https://"}, "Webhook URL must be an http or https URL"},
+		{
+			"bad start",
+			model.NotificationSettings{QuietHours: &model.QuietHours{Start: "10pm", End: "07:00", TimeZone: "UTC"}},
+			"Quiet hours start must be HH:MM",
+		},
+		{
+			"bad end",
+			model.NotificationSettings{QuietHours: &model.QuietHours{Start: "22:00", End: "7", TimeZone: "UTC"}},
+			"Quiet hours end must be HH:MM",
+		},
+		{
+			"empty window",
+			model.NotificationSettings{QuietHours: &model.QuietHours{Start: "22:00", End: "22:00", TimeZone: "UTC"}},
+			"Quiet hours must not start and end at the same time",
+		},
+		{
+			"missing time zone",
+			model.NotificationSettings{QuietHours: &model.QuietHours{Start: "22:00", End: "07:00"}},
+			"Quiet hours need a time zone",
+		},
+		{
+			"unknown time zone",
+			model.NotificationSettings{QuietHours: &model.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Mars/Base"}},
+			"Unknown time zone: Mars/Base",
+		},
+	}
+	for _, tt := range tests {
+		if got := validateNotificationSettings(tt.settings); got != tt.want {
+			t.Errorf("%s: validateNotificationSettings = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNotificationSubject(t *testing.T) {
+	for _, eventType := range model.NotificationTypes {
+		if _, ok := notificationSubjects[eventType]; !ok {
+			t.Errorf("no email subject for %s notifications", eventType)
+		}
+	}
+	if got := notificationSubject("unknown"); got != "New notification" {
+		t.Errorf("notificationSubject(unknown) = %q", got)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"0.0.0.0", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"198.18.0.1", false},
+		{"198.19.255.255", false},
+		{"192.0.0.8", false},
+		{"192.0.2.1", false},
+		{"203.0.113.9", false},
+		{"224.0.0.1", false},
+		{"240.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:100.64.0.1", false},
+		{"::ffff:93.184.216.34", true},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"ff02::1", false},
+		{"64:ff9b::a00:1", false},
+		{"2001:db8::1", false},
+		{"2001::1", false},
+		{"2002:a00:1::1", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	t.Setenv("NOTIFY_WEBHOOK_ALLOW_PRIVATE", "")
+	if _, err := webhookClient.Post(server.URL, "application/json", nil); !errors.Is(err, errPrivateAddress) {
+		t.Errorf("posting to loopback: error = %v, want errPrivateAddress", err)
+	}
+
+	t.Setenv("NOTIFY_WEBHOOK_ALLOW_PRIVATE", "true")
+	resp, err := webhookClient.Post(server.URL, "application/json", nil)
+	if err != nil {
+		t.Fatalf("posting to loopback with private addresses allowed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func updateNotificationSettings(t *testing.T, user model.User, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serve(UpdateNotificationSettings, as(request(t, http.MethodPut, "/api/users/me/notification-settings", json.RawMessage(body)), user.ID, "session"))
+}
+
+func TestUpdateNotificationSettings(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+
+	expect(t, updateNotificationSettings(t, user, `{"channels": {"comment": "email"}, "quiet_hours": {"start": "22:00", "end": "07:00", "time_zone": "UTC"}}`), http.StatusOK)
+	expect(t, updateNotificationSettings(t, user, `{"channels": {"mention": "none"}}`), http.StatusOK)
+	expect(t, updateNotificationSettings(t, user, `{"channels": {"due_soon": "webhook"}}`), http.StatusBadRequest)
+
+	var stored model.User
+	database.DB.First(&stored, user.ID)
+	settings := stored.Preferences.Notifications
+	want := map[string]string{model.NotificationComment: model.ChannelEmail, model.NotificationMention: model.ChannelNone}
+	if !reflect.DeepEqual(settings.Channels, want) || settings.QuietHours == nil {
+		t.Fatalf("settings = %+v, want channels merged and quiet hours kept", settings)
+	}
+
+	expect(t, updateNotificationSettings(t, user, `{"clear_quiet_hours": true}`), http.StatusOK)
+	database.DB.First(&stored, user.ID)
+	if stored.Preferences.Notifications.QuietHours != nil {
+		t.Error("quiet hours were not cleared")
+	}
+}
+
+func TestNotifyFollowsPreferences(t *testing.T) {
+	dbtest.Open(t)
+	now := time.Now().UTC()
+	quiet := &model.QuietHours{
+		Start:    now.Add(-time.Hour).Format("15:04"),
+		End:      now.Add(time.Hour).Format("15:04"),
+		TimeZone: "UTC",
+	}
+
+	tests := []struct {
+		name     string
+		settings model.NotificationSettings
+		inbox    bool
+	}{
+		{"in-app by default", model.NotificationSettings{}, true},
+		{"turned off", model.NotificationSettings{Channels: map[string]string{model.NotificationComment: model.ChannelNone}}, false},
+		{"by email", model.NotificationSettings{Channels: map[string]string{model.NotificationComment: model.ChannelEmail}}, false},
+		{
+			"by email during quiet hours",
+			model.NotificationSettings{Channels: map[string]string{model.NotificationComment: model.ChannelEmail}, QuietHours: quiet},
+			true,
+		},
+	}
+	for i, tt := range tests {
+		user := newUser(t, "Ada", "ada"+strconv.Itoa(i)+"@example.com")
+		user.Preferences.Notifications = tt.settings
+		database.DB.Select("Preferences").Save(&user)
+
+		notify(model.Notification{UserID: user.ID, Type: model.NotificationComment, Message: "New comment"})
+
+		var count int64
+		database.DB.Model(&model.Notification{}).Where("user_id = ?", user.ID).Count(&count)
+		if (count == 1) != tt.inbox {
+			t.Errorf("%s: %d inbox notifications, want inbox %t", tt.name, count, tt.inbox)
+		}
+	}
+}
+
+func TestNotifyUserWithoutStoredPreferences(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+	// Accounts that predate preferences have none until Migrate fills them in.
+	if err := database.DB.Exec("UPDATE users SET preferences = NULL WHERE id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(database.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	notify(model.Notification{UserID: user.ID, Type: model.NotificationComment, Message: "New comment"})
+
+	var count int64
+	database.DB.Model(&model.Notification{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("%d inbox notifications, want 1", count)
+	}
+}
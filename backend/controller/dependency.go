@@ -0,0 +1,383 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/websocket"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dependencyLockKey serializes changes to the dependency graph, so two
+// requests cannot each add half of a cycle, and keeps a task from being
+// completed while a blocker is being added to it.
+const dependencyLockKey = 0x7461736b646570
+
+// lockDependencies takes the dependency lock until tx ends. Checks against
+// the graph share it; changes to the graph hold it exclusively.
+func lockDependencies(tx *gorm.DB, exclusive bool) error {
+	if exclusive {
+		return tx.Exec("SELECT pg_advisory_xact_lock(?)", dependencyLockKey).Error
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock_shared(?)", dependencyLockKey).Error
+}
+
+// maxDependencyEdges bounds the graph returned for a task.
+const maxDependencyEdges = 1000
+
+// taskBlockedError rejects completing a task whose blockers are still open.
+type taskBlockedError struct {
+	blockers []uint
+}
+
+func (e *taskBlockedError) Error() string {
+	ids := make([]string, len(e.blockers))
+	for i, id := range e.blockers {
+		ids[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return "Task is blocked by open tasks: " + strings.Join(ids, ", ")
+}
+
+// openBlockers lists the tasks blocking taskID that are neither completed
+// nor in the trash.
+func openBlockers(db *gorm.DB, taskID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Model(&model.Task{}).
+		Joins("JOIN task_dependencies ON task_dependencies.blocker_id = tasks.id").
+		Where("task_dependencies.blocked_id = ? AND tasks.status <> ?", taskID, "completed").
+		Order("tasks.id").
+		Pluck("tasks.id", &ids).Error
+	return ids, err
+}
+
+// createsCycle reports whether blocker is already downstream of blocked, in
+// which case letting it block blocked would close a loop.
+func createsCycle(tx *gorm.DB, blockerID, blockedID uint) (bool, error) {
+	var exists bool
+	err := tx.Raw(`WITH RECURSIVE downstream(id) AS (
+			SELECT blocked_id FROM task_dependencies WHERE blocker_id = ?
+			UNION
+			SELECT d.blocked_id FROM task_dependencies d JOIN downstream s ON d.blocker_id = s.id
+		)
+		SELECT EXISTS (SELECT 1 FROM downstream WHERE id = ?)`, blockedID, blockerID).
+		Scan(&exists).Error
+	return exists, err
+}
+
+// syncBlockedStatus moves a task to "blocked" while it has open blockers and
+// back to its earlier status once they are done. Completed tasks, and tasks
+// set to "blocked" by hand, are left alone.
+func syncBlockedStatus(r *http.Request, taskID, actorID uint) {
+	var task model.Task
+	if err := database.DB.Where("id = ?", taskID).First(&task).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			log.Printf("Could not load task %d to update its blocked status: %v", taskID, err)
+		}
+		return
+	}
+
+	blockers, err := openBlockers(database.DB, taskID)
+	if err != nil {
+		log.Printf("Could not load blockers of task %d: %v", taskID, err)
+		return
+	}
+
+	before := task
+	switch {
+	case len(blockers) > 0 && task.Status != "blocked" && task.Status != "completed":
+		task.StatusBeforeBlocked = task.Status
+		task.Status = "blocked"
+	case len(blockers) == 0 && task.Status == "blocked" && task.StatusBeforeBlocked != "":
+		task.Status = task.StatusBeforeBlocked
+		task.StatusBeforeBlocked = ""
+	default:
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveTask(tx, &task); err != nil {
+			return err
+		}
+		return recordTaskHistory(tx, r, task, model.TaskActionUpdated, taskChanges(&before, task))
+	})
+	if err != nil {
+		log.Printf("Could not update blocked status of task %d: %v", taskID, err)
+		return
+	}
+
+	websocket.BroadcastTaskUpdate(task, "updated", taskWatchers(&task))
+	notifyTaskChanges(&task, &before, actorID)
+}
+
+// syncDependents updates the blocked status of the tasks blockerID blocks,
+// after it was completed, reopened, deleted or restored.
+func syncDependents(r *http.Request, blockerID, actorID uint) {
+	var blocked []uint
+	if err := database.DB.Model(&model.TaskDependency{}).Where("blocker_id = ?", blockerID).Pluck("blocked_id", &blocked).Error; err != nil {
+		log.Printf("Could not load tasks blocked by %d: %v", blockerID, err)
+		return
+	}
+	for _, taskID := range blocked {
+		syncBlockedStatus(r, taskID, actorID)
+	}
+}
+
+// writeTaskBlocked reports an attempt to complete a blocked task.
+func writeTaskBlocked(w http.ResponseWriter, err error) bool {
+	var blocked *taskBlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+	http.Error(w, blocked.Error(), http.StatusConflict)
+	return true
+}
+
+// findTask loads a task within scope, writing an error response if it is
+// not there.
+func findTask(w http.ResponseWriter, scope func(*gorm.DB) *gorm.DB, taskID interface{}) (*model.Task, bool) {
+	var task model.Task
+	if err := database.DB.Scopes(scope).Where("tasks.id = ?", taskID).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &task, true
+}
+
+type dependencyEdge struct {
+	BlockerID uint `json:"blocker_id"`
+	BlockedID uint `json:"blocked_id"`
+}
+
+type dependencyNode struct {
+	ID       uint   `json:"id"`
+	Title    string `json:"title,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	// Hidden tasks are in the graph but the caller cannot see them.
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+// GetTaskDependencies returns every task upstream of the task (what blocks
+// it, directly or not) and downstream of it (what it blocks), with the edges
+// between them. Tasks in the trash are left out.
+func GetTaskDependencies(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := findTask(w, visibleTasks(userID), r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	var edges []dependencyEdge
+	err := database.DB.Raw(`WITH RECURSIVE upstream(blocker_id, blocked_id) AS (
+			SELECT d.blocker_id, d.blocked_id FROM task_dependencies d
+				JOIN tasks t ON t.id = d.blocker_id AND t.deleted_at IS NULL
+				WHERE d.blocked_id = ?
+			UNION
+			SELECT d.blocker_id, d.blocked_id FROM task_dependencies d
+				JOIN upstream u ON d.blocked_id = u.blocker_id
+				JOIN tasks t ON t.id = d.blocker_id AND t.deleted_at IS NULL
+		), downstream(blocker_id, blocked_id) AS (
+			SELECT d.blocker_id, d.blocked_id FROM task_dependencies d
+				JOIN tasks t ON t.id = d.blocked_id AND t.deleted_at IS NULL
+				WHERE d.blocker_id = ?
+			UNION
+			SELECT d.blocker_id, d.blocked_id FROM task_dependencies d
+				JOIN downstream s ON d.blocker_id = s.blocked_id
+				JOIN tasks t ON t.id = d.blocked_id AND t.deleted_at IS NULL
+		)
+		SELECT blocker_id, blocked_id FROM upstream
+		UNION
+		SELECT blocker_id, blocked_id FROM downstream
+		ORDER BY blocker_id, blocked_id
+		LIMIT ?`, task.ID, task.ID, maxDependencyEdges).
+		Scan(&edges).Error
+	if err != nil {
+		http.Error(w, "Could not retrieve dependencies", http.StatusInternalServerError)
+		return
+	}
+
+	ids := []uint{task.ID}
+	seen := map[uint]bool{task.ID: true}
+	blockedBy, blocks := []uint{}, []uint{}
+	for _, edge := range edges {
+		for _, id := range []uint{edge.BlockerID, edge.BlockedID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if edge.BlockedID == task.ID {
+			blockedBy = append(blockedBy, edge.BlockerID)
+		}
+		if edge.BlockerID == task.ID {
+			blocks = append(blocks, edge.BlockedID)
+		}
+	}
+
+	var visible []model.Task
+	if err := database.DB.Scopes(visibleTasks(userID)).Where("tasks.id IN ?", ids).Find(&visible).Error; err != nil {
+		http.Error(w, "Could not retrieve dependencies", http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[uint]model.Task, len(visible))
+	for _, t := range visible {
+		byID[t.ID] = t
+	}
+
+	nodes := make([]dependencyNode, 0, len(ids))
+	for _, id := range ids {
+		t, ok := byID[id]
+		if !ok {
+			nodes = append(nodes, dependencyNode{ID: id, Hidden: true})
+			continue
+		}
+		nodes = append(nodes, dependencyNode{ID: t.ID, Title: t.Title, Status: t.Status, Priority: t.Priority})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"task_id":    task.ID,
+		"blocked_by": blockedBy,
+		"blocks":     blocks,
+		"nodes":      nodes,
+		"edges":      edges,
+		"truncated":  len(edges) == maxDependencyEdges,
+	})
+}
+
+// AddTaskDependency records that the task is blocked by, or blocks, another
+// one. The caller must be able to edit the blocked task and see the blocker.
+func AddTaskDependency(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input model.TaskDependencyInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+	if (input.BlockedBy == 0) == (input.Blocks == 0) {
+		http.Error(w, "Set exactly one of blocked_by and blocks", http.StatusBadRequest)
+		return
+	}
+
+	var blocker, blocked *model.Task
+	if input.BlockedBy != 0 {
+		if blocked, ok = findTask(w, editableTasks(userID), r.PathValue("id")); !ok {
+			return
+		}
+		if blocker, ok = findTask(w, visibleTasks(userID), input.BlockedBy); !ok {
+			return
+		}
+	} else {
+		if blocker, ok = findTask(w, visibleTasks(userID), r.PathValue("id")); !ok {
+			return
+		}
+		if blocked, ok = findTask(w, editableTasks(userID), input.Blocks); !ok {
+			return
+		}
+	}
+
+	if blocker.ID == blocked.ID {
+		http.Error(w, "A task cannot block itself", http.StatusBadRequest)
+		return
+	}
+
+	dependency := model.TaskDependency{BlockerID: blocker.ID, BlockedID: blocked.ID, CreatedBy: userID}
+	errCycle := errors.New("dependency cycle")
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockDependencies(tx, true); err != nil {
+			return err
+		}
+		cycle, err := createsCycle(tx, blocker.ID, blocked.ID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return errCycle
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&dependency).Error
+	})
+	if err != nil {
+		if err == errCycle {
+			http.Error(w, fmt.Sprintf("Task %d already depends on task %d", blocker.ID, blocked.ID), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not add dependency", http.StatusInternalServerError)
+		return
+	}
+
+	syncBlockedStatus(r, blocked.ID, userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"dependency": dependency})
+}
+
+// RemoveTaskDependency removes the relation between the task and another
+// one, whichever way round it goes.
+func RemoveTaskDependency(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := findTask(w, visibleTasks(userID), r.PathValue("id"))
+	if !ok {
+		return
+	}
+	otherID, err := strconv.ParseUint(r.PathValue("otherId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var dependency model.TaskDependency
+	err = database.DB.
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", task.ID, otherID, otherID, task.ID).
+		First(&dependency).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Dependency not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if _, ok := findTask(w, editableTasks(userID), dependency.BlockedID); !ok {
+		return
+	}
+
+	if err := database.DB.Where("blocker_id = ? AND blocked_id = ?", dependency.BlockerID, dependency.BlockedID).Delete(&model.TaskDependency{}).Error; err != nil {
+		http.Error(w, "Could not remove dependency", http.StatusInternalServerError)
+		return
+	}
+
+	syncBlockedStatus(r, dependency.BlockedID, userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Dependency removed successfully"})
+}
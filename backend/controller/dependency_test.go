@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestWriteTaskBlocked(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		handled bool
+		body    string
+	}{
+		{"one blocker", &taskBlockedError{blockers: []uint{4}}, true, "Task is blocked by open tasks: 4"},
+		{"several blockers", &taskBlockedError{blockers: []uint{4, 9, 12}}, true, "Task is blocked by open tasks: 4, 9, 12"},
+		{"wrapped", fmt.Errorf("update: %w", &taskBlockedError{blockers: []uint{2}}), true, "Task is blocked by open tasks: 2"},
+		{"other error", errors.New("boom"), false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if got := writeTaskBlocked(rec, tt.err); got != tt.handled {
+				t.Fatalf("writeTaskBlocked = %t, want %t", got, tt.handled)
+			}
+			if !tt.handled {
+				if rec.Body.Len() != 0 {
+					t.Errorf("wrote %q for an unrelated error", rec.Body.String())
+				}
+				return
+			}
+			if rec.Code != http.StatusConflict {
+				t.Errorf("status = %d, want 409", rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+// depend relates the task to another one as user, through the API.
+func depend(t *testing.T, user model.User, task model.Task, body map[string]uint) *httptest.ResponseRecorder {
+	t.Helper()
+	return serve(AddTaskDependency, taskRequest(t, http.MethodPost, user, task.ID, body))
+}
+
+// reload reads back a task with the fields the API leaves out.
+func reload(t *testing.T, task model.Task) model.Task {
+	t.Helper()
+	var current model.Task
+	if err := database.DB.Unscoped().First(&current, task.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return current
+}
+
+func setStatus(t *testing.T, user model.User, task model.Task, status string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serve(PatchTask, taskRequest(t, http.MethodPatch, user, task.ID, map[string]string{"status": status}))
+}
+
+func TestAddTaskDependencyRejectsCycles(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	var tasks []model.Task
+	for _, title := range []string{"A", "B", "C", "D"} {
+		tasks = append(tasks, newTask(t, owner, map[string]interface{}{"title": title, "status": "todo"}))
+	}
+	a, b, c, d := tasks[0], tasks[1], tasks[2], tasks[3]
+
+	expect(t, depend(t, owner, b, map[string]uint{"blocked_by": a.ID}), http.StatusCreated)
+	expect(t, depend(t, owner, a, map[string]uint{"blocked_by": b.ID}), http.StatusConflict)
+	expect(t, depend(t, owner, b, map[string]uint{"blocks": a.ID}), http.StatusConflict)
+	expect(t, depend(t, owner, a, map[string]uint{"blocked_by": a.ID}), http.StatusBadRequest)
+
+	// A blocks B blocks C blocks D, so D cannot block A either way round.
+	expect(t, depend(t, owner, b, map[string]uint{"blocks": c.ID}), http.StatusCreated)
+	expect(t, depend(t, owner, d, map[string]uint{"blocked_by": c.ID}), http.StatusCreated)
+	expect(t, depend(t, owner, a, map[string]uint{"blocked_by": d.ID}), http.StatusConflict)
+	expect(t, depend(t, owner, d, map[string]uint{"blocks": a.ID}), http.StatusConflict)
+	expect(t, depend(t, owner, c, map[string]uint{"blocks": b.ID}), http.StatusConflict)
+
+	// Blocking a task again, or from further upstream, closes no loop.
+	expect(t, depend(t, owner, b, map[string]uint{"blocked_by": a.ID}), http.StatusCreated)
+	expect(t, depend(t, owner, d, map[string]uint{"blocked_by": a.ID}), http.StatusCreated)
+
+	var count int64
+	database.DB.Model(&model.TaskDependency{}).Count(&count)
+	if count != 4 {
+		t.Errorf("%d dependencies stored, want 4", count)
+	}
+}
+
+func TestAddTaskDependencyPermissions(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	stranger := newUser(t, "Eve", "eve@example.com")
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report"})
+	other := newTask(t, stranger, map[string]interface{}{"title": "Secret"})
+
+	expect(t, depend(t, owner, task, map[string]uint{"blocked_by": other.ID}), http.StatusNotFound)
+	expect(t, depend(t, owner, task, map[string]uint{"blocks": other.ID}), http.StatusNotFound)
+	expect(t, depend(t, stranger, other, map[string]uint{"blocked_by": task.ID}), http.StatusNotFound)
+	expect(t, depend(t, owner, task, map[string]uint{"blocked_by": other.ID, "blocks": other.ID}), http.StatusBadRequest)
+	expect(t, depend(t, owner, task, map[string]uint{}), http.StatusBadRequest)
+}
+
+func TestBlockedTaskCannotBeCompleted(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	blocker := newTask(t, owner, map[string]interface{}{"title": "Gather data", "status": "todo"})
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report", "status": "todo"})
+
+	expect(t, depend(t, owner, task, map[string]uint{"blocked_by": blocker.ID}), http.StatusCreated)
+	if got := reload(t, task); got.Status != "blocked" || got.StatusBeforeBlocked != "todo" {
+		t.Fatalf("status = %q before %q, want blocked before todo", got.Status, got.StatusBeforeBlocked)
+	}
+
+	rec := setStatus(t, owner, task, "completed")
+	expect(t, rec, http.StatusConflict)
+	if want := fmt.Sprintf("Task is blocked by open tasks: %d", blocker.ID); strings.TrimSpace(rec.Body.String()) != want {
+		t.Errorf("body = %q, want %q", rec.Body.String(), want)
+	}
+	if got := reload(t, task); got.Status != "blocked" {
+		t.Errorf("status = %q after a rejected completion, want blocked", got.Status)
+	}
+
+	// Other statuses wait for the blockers to be done.
+	expect(t, setStatus(t, owner, task, "in_progress"), http.StatusOK)
+	if got := reload(t, task); got.Status != "blocked" || got.StatusBeforeBlocked != "in_progress" {
+		t.Fatalf("status = %q before %q, want blocked before in_progress", got.Status, got.StatusBeforeBlocked)
+	}
+
+	expect(t, setStatus(t, owner, blocker, "completed"), http.StatusOK)
+	if got := reload(t, task); got.Status != "in_progress" || got.StatusBeforeBlocked != "" {
+		t.Fatalf("status = %q before %q once unblocked, want in_progress", got.Status, got.StatusBeforeBlocked)
+	}
+	expect(t, setStatus(t, owner, task, "completed"), http.StatusOK)
+
+	// Reopening the blocker leaves a completed task alone.
+	expect(t, setStatus(t, owner, blocker, "todo"), http.StatusOK)
+	if got := reload(t, task); got.Status != "completed" {
+		t.Errorf("status = %q after reopening the blocker, want completed", got.Status)
+	}
+}
+
+func TestBlockedStatusFollowsBlockers(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	first := newTask(t, owner, map[string]interface{}{"title": "Gather data", "status": "todo"})
+	second := newTask(t, owner, map[string]interface{}{"title": "Check data", "status": "todo"})
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report", "status": "in_progress"})
+
+	expect(t, depend(t, owner, task, map[string]uint{"blocked_by": first.ID}), http.StatusCreated)
+	expect(t, depend(t, owner, second, map[string]uint{"blocks": task.ID}), http.StatusCreated)
+
+	// The task stays blocked until its last open blocker is done.
+	expect(t, setStatus(t, owner, first, "completed"), http.StatusOK)
+	if got := reload(t, task); got.Status != "blocked" {
+		t.Fatalf("status = %q with a blocker left, want blocked", got.Status)
+	}
+	expect(t, serve(DeleteTask, taskRequest(t, http.MethodDelete, owner, second.ID, nil)), http.StatusOK)
+	if got := reload(t, task); got.Status != "in_progress" || got.StatusBeforeBlocked != "" {
+		t.Fatalf("status = %q before %q once the blocker was trashed, want in_progress", got.Status, got.StatusBeforeBlocked)
+	}
+	expect(t, serve(RestoreTask, taskRequest(t, http.MethodPost, owner, second.ID, nil)), http.StatusOK)
+	if got := reload(t, task); got.Status != "blocked" || got.StatusBeforeBlocked != "in_progress" {
+		t.Fatalf("status = %q before %q once the blocker was restored, want blocked before in_progress", got.Status, got.StatusBeforeBlocked)
+	}
+
+	r := taskRequest(t, http.MethodDelete, owner, task.ID, nil)
+	r.SetPathValue("otherId", strconv.FormatUint(uint64(second.ID), 10))
+	expect(t, serve(RemoveTaskDependency, r), http.StatusOK)
+	if got := reload(t, task); got.Status != "in_progress" {
+		t.Fatalf("status = %q once the dependency was removed, want in_progress", got.Status)
+	}
+
+	// A task set to "blocked" by hand is not moved out of it.
+	expect(t, setStatus(t, owner, task, "blocked"), http.StatusOK)
+	expect(t, setStatus(t, owner, first, "todo"), http.StatusOK)
+	expect(t, setStatus(t, owner, first, "completed"), http.StatusOK)
+	if got := reload(t, task); got.Status != "blocked" {
+		t.Errorf("status = %q, want the hand-set blocked kept", got.Status)
+	}
+}
+
+func TestGetTaskDependencies(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	stranger := newUser(t, "Eve", "eve@example.com")
+	upstream := newTask(t, owner, map[string]interface{}{"title": "Gather data"})
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report"})
+	downstream := newTask(t, owner, map[string]interface{}{"title": "Send report"})
+	hidden := newTask(t, stranger, map[string]interface{}{"title": "Secret"})
+
+	expect(t, depend(t, owner, task, map[string]uint{"blocked_by": upstream.ID}), http.StatusCreated)
+	expect(t, depend(t, owner, task, map[string]uint{"blocks": downstream.ID}), http.StatusCreated)
+	// The owner cannot see the stranger's task, so it can only get into the
+	// graph from the stranger's side.
+	if err := database.DB.Create(&model.TaskDependency{BlockerID: hidden.ID, BlockedID: upstream.ID, CreatedBy: stranger.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	type graph struct {
+		BlockedBy []uint           `json:"blocked_by"`
+		Blocks    []uint           `json:"blocks"`
+		Nodes     []dependencyNode `json:"nodes"`
+		Edges     []dependencyEdge `json:"edges"`
+	}
+	load := func() graph {
+		rec := serve(GetTaskDependencies, taskRequest(t, http.MethodGet, owner, task.ID, nil))
+		expect(t, rec, http.StatusOK)
+		var g graph
+		decode(t, rec, &g)
+		return g
+	}
+
+	g := load()
+	if len(g.BlockedBy) != 1 || g.BlockedBy[0] != upstream.ID || len(g.Blocks) != 1 || g.Blocks[0] != downstream.ID {
+		t.Errorf("blocked by %v and blocks %v, want %d and %d", g.BlockedBy, g.Blocks, upstream.ID, downstream.ID)
+	}
+	if len(g.Edges) != 3 {
+		t.Errorf("edges = %+v, want the 3 of the chain", g.Edges)
+	}
+	nodes := make(map[uint]dependencyNode)
+	for _, node := range g.Nodes {
+		nodes[node.ID] = node
+	}
+	if len(nodes) != 4 {
+		t.Errorf("nodes = %+v, want the 4 tasks of the chain", g.Nodes)
+	}
+	if node := nodes[hidden.ID]; !node.Hidden || node.Title != "" || node.Status != "" {
+		t.Errorf("stranger's task = %+v, want it hidden without details", node)
+	}
+	if node := nodes[upstream.ID]; node.Hidden || node.Title != "Gather data" {
+		t.Errorf("upstream task = %+v, want it shown", node)
+	}
+
+	expect(t, serve(GetTaskDependencies, taskRequest(t, http.MethodGet, stranger, task.ID, nil)), http.StatusNotFound)
+
+	// Trashed tasks drop out of the graph, along with what lies beyond them.
+	expect(t, serve(DeleteTask, taskRequest(t, http.MethodDelete, owner, upstream.ID, nil)), http.StatusOK)
+	g = load()
+	if len(g.BlockedBy) != 0 || len(g.Edges) != 1 || len(g.Nodes) != 2 {
+		t.Errorf("graph = %+v, want only the downstream task left", g)
+	}
+}
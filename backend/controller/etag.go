@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+)
+
+var errTaskVersionConflict = errors.New("task was modified concurrently")
+
+// taskUpdatableFields are the columns written when a task is modified.
+var taskUpdatableFields = []string{"Title", "Description", "Status", "Priority", "DueDate", "AssignedTo", "StatusBeforeBlocked", "Version", "UpdatedAt"}
+
+func taskETag(task model.Task) string {
+	return fmt.Sprintf(`"%d"`, task.Version)
+}
+
+// etagMatches reports whether header, a comma separated list of entity tags
+// or "*", matches etag. Weak comparison, used for If-None-Match, lets weak tags
+// match by their opaque value; under strong comparison, used for If-Match, a
+// weak tag never matches (RFC 9110 section 8.8.3.2).
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch writes 412 and returns false when the request carries an
+// If-Match header that does not match the task's current version.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, task model.Task) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatches(header, taskETag(task), false) {
+		return true
+	}
+	w.Header().Set("ETag", taskETag(task))
+	http.Error(w, "Task has been modified, reload and try again", http.StatusPreconditionFailed)
+	return false
+}
+
+// writeVersionConflict reports a lost race between reading and writing a task.
+func writeVersionConflict(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("If-Match") != "" {
+		http.Error(w, "Task has been modified, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	http.Error(w, "Task has been modified, reload and try again", http.StatusConflict)
+}
+
+// saveTask writes the task's updatable fields only if nobody changed it since
+// it was read, and bumps its version.
+func saveTask(tx *gorm.DB, task *model.Task) error {
+	readVersion := task.Version
+	task.Version++
+
+	result := tx.Model(task).
+		Where("version = ?", readVersion).
+		Select(taskUpdatableFields).
+		Updates(task)
+	if result.Error != nil {
+		task.Version = readVersion
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		task.Version = readVersion
+		return errTaskVersionConflict
+	}
+	return nil
+}
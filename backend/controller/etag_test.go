@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"3"`, false, true},
+		{`"3"`, true, true},
+		{`"4"`, false, false},
+		{`*`, false, true},
+		{`"1", "3"`, false, true},
+		{` "1" ,"3" `, true, true},
+		{`W/"3"`, true, true},
+		{`W/"3"`, false, false},
+		{`"1", W/"3"`, false, false},
+		{`3`, false, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"3"`, tt.weak); got != tt.want {
+			t.Errorf("etagMatches(%q, weak=%t) = %t, want %t", tt.header, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	task := model.Task{ID: 1, Version: 3}
+	tests := []struct {
+		header string
+		ok     bool
+	}{
+		{"", true},
+		{`"3"`, true},
+		{`"2"`, false},
+		{`W/"3"`, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/api/tasks/1/", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+		w := httptest.NewRecorder()
+
+		if got := checkIfMatch(w, r, task); got != tt.ok {
+			t.Errorf("checkIfMatch with %q = %t, want %t", tt.header, got, tt.ok)
+		}
+		if !tt.ok {
+			if w.Code != http.StatusPreconditionFailed {
+				t.Errorf("status with %q = %d, want 412", tt.header, w.Code)
+			}
+			if etag := w.Header().Get("ETag"); etag != `"3"` {
+				t.Errorf("ETag with %q = %q, want the current version", tt.header, etag)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+// newUser stores a user with a verified email and default preferences.
+func newUser(t *testing.T, name, email string) model.User {
+	t.Helper()
+	user := model.User{
+		Name:          name,
+		Email:         email,
+		EmailVerified: true,
+		Preferences:   model.DefaultPreferences(),
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("create user %s: %v", email, err)
+	}
+	return user
+}
+
+// request builds a request with body encoded as JSON, unless it is nil.
+func request(t *testing.T, method, target string, body interface{}) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return httptest.NewRequest(method, target, &buf)
+}
+
+// as authenticates r the way AuthMiddleware does for a login session.
+func as(r *http.Request, userID uint, sessionID string) *http.Request {
+	ctx := context.WithValue(r.Context(), middleware.UserIDKey, userID)
+	ctx = context.WithValue(ctx, middleware.SessionIDKey, sessionID)
+	ctx = context.WithValue(ctx, middleware.UserRoleKey, model.RoleUser)
+	return r.WithContext(ctx)
+}
+
+func serve(handler http.HandlerFunc, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	return rec
+}
+
+// expect fails the test unless rec has the given status.
+func expect(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, status, rec.Body.String())
+	}
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+}
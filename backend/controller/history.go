@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+)
+
+// taskChanges lists the editable fields that differ between before and after.
+// A nil before records every field of a newly created task.
+func taskChanges(before *model.Task, after model.Task) map[string]model.FieldChange {
+	if before == nil {
+		before = &model.Task{}
+	}
+
+	changes := map[string]model.FieldChange{}
+	add := func(field string, old, new interface{}) {
+		changes[field] = model.FieldChange{Old: old, New: new}
+	}
+
+	if before.Title != after.Title {
+		add("title", before.Title, after.Title)
+	}
+	if before.Description != after.Description {
+		add("description", before.Description, after.Description)
+	}
+	if before.Status != after.Status {
+		add("status", before.Status, after.Status)
+	}
+	if before.Priority != after.Priority {
+		add("priority", before.Priority, after.Priority)
+	}
+	if !sameTime(before.DueDate, after.DueDate) {
+		add("due_date", before.DueDate, after.DueDate)
+	}
+	if before.AssignedTo != after.AssignedTo {
+		add("assigned_to", before.AssignedTo, after.AssignedTo)
+	}
+	return changes
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// recordTaskHistory appends an audit entry for the request's change to a
+// task and snapshots the task as a new revision. task must hold the state
+// after the change, and the call should run in the same transaction.
+func recordTaskHistory(tx *gorm.DB, r *http.Request, task model.Task, action string, changes map[string]model.FieldChange) error {
+	actorID, _ := r.Context().Value(middleware.UserIDKey).(uint)
+	requestID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	revision := model.TaskRevision{
+		TaskID:      task.ID,
+		Version:     task.Version,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		Priority:    task.Priority,
+		DueDate:     task.DueDate,
+		AssignedTo:  task.AssignedTo,
+		ActorID:     actorID,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return err
+	}
+
+	entry := model.TaskHistory{
+		TaskID:    task.ID,
+		ActorID:   actorID,
+		Action:    action,
+		Changes:   changes,
+		RequestID: requestID,
+	}
+	if impersonatorID, ok := r.Context().Value(middleware.ImpersonatorIDKey).(uint); ok {
+		entry.ImpersonatorID = &impersonatorID
+	}
+	return tx.Create(&entry).Error
+}
+
+// GetTaskHistory lists a task's audit trail, newest first. Pages are
+// requested with limit and the next_cursor of the previous page.
+func GetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID := r.PathValue("id")
+
+	// History stays readable while the task is in the trash.
+	var task model.Task
+	if err := database.DB.Unscoped().Scopes(visibleTasks(userID)).Where("tasks.id = ?", taskID).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	limit, _ := parsePagination(r, 50, 200)
+
+	query := database.DB.Where("task_id = ?", task.ID)
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		before, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("id < ?", before)
+	}
+
+	var entries []model.TaskHistory
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&entries).Error; err != nil {
+		http.Error(w, "Could not retrieve task history", http.StatusInternalServerError)
+		return
+	}
+
+	var nextCursor string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		nextCursor = strconv.FormatUint(uint64(entries[limit-1].ID), 10)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"history":     entries,
+		"next_cursor": nextCursor,
+	})
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestTaskChanges(t *testing.T) {
+	due := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	sameDue := due.In(time.FixedZone("UTC+2", 2*60*60))
+	later := due.Add(time.Hour)
+
+	before := model.Task{Title: "a", Description: "d", Status: "pending", Priority: "low", DueDate: &due, AssignedTo: 1}
+
+	tests := []struct {
+		name   string
+		before *model.Task
+		after  model.Task
+		want   map[string]model.FieldChange
+	}{
+		{
+			name:   "no changes",
+			before: &before,
+			after:  before,
+			want:   map[string]model.FieldChange{},
+		},
+		{
+			name:   "same instant in another zone",
+			before: &before,
+			after:  model.Task{Title: "a", Description: "d", Status: "pending", Priority: "low", DueDate: &sameDue, AssignedTo: 1},
+			want:   map[string]model.FieldChange{},
+		},
+		{
+			name:   "changed fields only",
+			before: &before,
+			after:  model.Task{Title: "b", Description: "d", Status: "completed", Priority: "low", DueDate: &later, AssignedTo: 1},
+			want: map[string]model.FieldChange{
+				"title":    {Old: "a", New: "b"},
+				"status":   {Old: "pending", New: "completed"},
+				"due_date": {Old: &due, New: &later},
+			},
+		},
+		{
+			name:   "cleared due date and assignee",
+			before: &before,
+			after:  model.Task{Title: "a", Description: "d", Status: "pending", Priority: "low"},
+			want: map[string]model.FieldChange{
+				"due_date":    {Old: &due, New: (*time.Time)(nil)},
+				"assigned_to": {Old: uint(1), New: uint(0)},
+			},
+		},
+		{
+			name:   "new task records every set field",
+			before: nil,
+			after:  model.Task{Title: "a", Status: "pending"},
+			want: map[string]model.FieldChange{
+				"title":  {Old: "", New: "a"},
+				"status": {Old: "", New: "pending"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := taskChanges(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("taskChanges = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,563 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var labelColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+var errLabelNameTaken = errors.New("label name already in use")
+
+// labelNameTaken reports whether another label in the workspace already has
+// the name, ignoring case.
+func labelNameTaken(tx *gorm.DB, workspaceID uint, name string, exceptID uint) (bool, error) {
+	var count int64
+	err := tx.Model(&model.Label{}).
+		Where("workspace_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", workspaceID, name, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// taskLabel is how a label appears in a task's history.
+type taskLabel struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// labelledTasks lists the tasks carrying any of the labels.
+func labelledTasks(tx *gorm.DB, labelIDs ...uint) ([]uint, error) {
+	var taskIDs []uint
+	err := tx.Raw("SELECT DISTINCT task_id FROM task_labels WHERE label_id IN ?", labelIDs).Scan(&taskIDs).Error
+	return taskIDs, err
+}
+
+// taskLabels returns the labels on each of the tasks.
+func taskLabels(tx *gorm.DB, taskIDs []uint) (map[uint][]taskLabel, error) {
+	var rows []struct {
+		TaskID uint
+		ID     uint
+		Name   string
+		Color  string
+	}
+	err := tx.Raw(
+		`SELECT task_labels.task_id, labels.id, labels.name, labels.color
+		FROM task_labels JOIN labels ON labels.id = task_labels.label_id
+		WHERE task_labels.task_id IN ?
+		ORDER BY task_labels.task_id, labels.id`, taskIDs).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[uint][]taskLabel, len(taskIDs))
+	for _, row := range rows {
+		labels[row.TaskID] = append(labels[row.TaskID], taskLabel{ID: row.ID, Name: row.Name, Color: row.Color})
+	}
+	return labels, nil
+}
+
+// changeTaskLabels runs change, which alters the labels of the tasks, and
+// then bumps the version of each task whose labels differ and records the
+// change in its history, so ETags and history follow label changes. It must
+// run inside a transaction.
+func changeTaskLabels(tx *gorm.DB, r *http.Request, taskIDs []uint, change func() error) error {
+	if len(taskIDs) == 0 {
+		return change()
+	}
+
+	var tasks []model.Task
+	err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", taskIDs).Order("id").Find(&tasks).Error
+	if err != nil {
+		return err
+	}
+	before, err := taskLabels(tx, taskIDs)
+	if err != nil {
+		return err
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	after, err := taskLabels(tx, taskIDs)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if slices.Equal(before[task.ID], after[task.ID]) {
+			continue
+		}
+		task.Version++
+		// Trashed tasks keep their labels too, and must not fall behind the
+		// revisions recorded for them.
+		result := tx.Unscoped().Model(&task).UpdateColumn("version", task.Version)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("task %d disappeared while its labels changed", task.ID)
+		}
+		changes := map[string]model.FieldChange{
+			"labels": {Old: labelList(before[task.ID]), New: labelList(after[task.ID])},
+		}
+		if err := recordTaskHistory(tx, r, task, model.TaskActionUpdated, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// labelList keeps an empty label set from being recorded as null.
+func labelList(labels []taskLabel) []taskLabel {
+	if labels == nil {
+		return []taskLabel{}
+	}
+	return labels
+}
+
+// loadLabel loads the label named in the path and the caller's role in its
+// workspace, writing an error response if the caller is not a member.
+func loadLabel(w http.ResponseWriter, r *http.Request, userID uint) (*model.Label, string, bool) {
+	var label model.Label
+	if err := database.DB.Where("id = ?", r.PathValue("id")).First(&label).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Label not found", http.StatusNotFound)
+			return nil, "", false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, "", false
+	}
+
+	role, err := workspaceRole(label.WorkspaceID, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, "", false
+	}
+	if role == "" {
+		http.Error(w, "Label not found", http.StatusNotFound)
+		return nil, "", false
+	}
+	return &label, role, true
+}
+
+func GetLabels(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workspaceID := r.PathValue("id")
+
+	role, err := workspaceRole(workspaceID, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if role == "" {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	var labels []model.Label
+	if err := database.DB.Where("workspace_id = ?", workspaceID).Order("name").Find(&labels).Error; err != nil {
+		http.Error(w, "Could not retrieve labels", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"labels": labels})
+}
+
+func CreateLabel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workspaceID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	role, err := workspaceRole(workspaceID, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if role == "" {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	var input model.LabelInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	label := model.Label{
+		WorkspaceID: uint(workspaceID),
+		Name:        strings.TrimSpace(input.Name),
+		Color:       input.Color,
+	}
+	if label.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	if label.Color == "" {
+		label.Color = model.DefaultLabelColor
+	}
+	if !labelColorPattern.MatchString(label.Color) {
+		http.Error(w, "Color must be a hex color such as #1f6feb", http.StatusBadRequest)
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		taken, err := labelNameTaken(tx, label.WorkspaceID, label.Name, 0)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return errLabelNameTaken
+		}
+		return tx.Create(&label).Error
+	})
+	if err != nil {
+		if err == errLabelNameTaken {
+			http.Error(w, "A label with this name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not create label", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"label": label})
+}
+
+// UpdateLabel renames or recolors a label. Tasks reference labels by ID, so
+// a rename applies to every task at once, which is why only workspace admins
+// may do it.
+func UpdateLabel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	label, role, ok := loadLabel(w, r, userID)
+	if !ok {
+		return
+	}
+	if !canManageWorkspace(role) {
+		http.Error(w, "Only workspace admins can change labels", http.StatusForbidden)
+		return
+	}
+
+	var input model.LabelUpdateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	if input.Name != nil {
+		label.Name = strings.TrimSpace(*input.Name)
+		if label.Name == "" {
+			http.Error(w, "Name cannot be empty", http.StatusBadRequest)
+			return
+		}
+	}
+	if input.Color != nil {
+		if !labelColorPattern.MatchString(*input.Color) {
+			http.Error(w, "Color must be a hex color such as #1f6feb", http.StatusBadRequest)
+			return
+		}
+		label.Color = *input.Color
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		taken, err := labelNameTaken(tx, label.WorkspaceID, label.Name, label.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return errLabelNameTaken
+		}
+		taskIDs, err := labelledTasks(tx, label.ID)
+		if err != nil {
+			return err
+		}
+		return changeTaskLabels(tx, r, taskIDs, func() error {
+			return tx.Select("Name", "Color", "UpdatedAt").Save(label).Error
+		})
+	})
+	if err != nil {
+		if err == errLabelNameTaken {
+			http.Error(w, "A label with this name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not update label", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"label": label})
+}
+
+// MergeLabel moves every task from one label to another in the same
+// workspace and deletes the first, in a single transaction.
+func MergeLabel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	source, role, ok := loadLabel(w, r, userID)
+	if !ok {
+		return
+	}
+	if !canManageWorkspace(role) {
+		http.Error(w, "Only workspace admins can merge labels", http.StatusForbidden)
+		return
+	}
+
+	var input model.LabelMergeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Into == 0 {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+	if input.Into == source.ID {
+		http.Error(w, "A label cannot be merged into itself", http.StatusBadRequest)
+		return
+	}
+
+	var target model.Label
+	if err := database.DB.Where("id = ? AND workspace_id = ?", input.Into, source.WorkspaceID).First(&target).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Target label not found in this workspace", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var moved int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock both labels so concurrent merges or deletes cannot interleave.
+		var locked []model.Label
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", []uint{source.ID, target.ID}).Find(&locked).Error; err != nil {
+			return err
+		}
+		if len(locked) != 2 {
+			return gorm.ErrRecordNotFound
+		}
+
+		taskIDs, err := labelledTasks(tx, source.ID)
+		if err != nil {
+			return err
+		}
+		return changeTaskLabels(tx, r, taskIDs, func() error {
+			result := tx.Exec(
+				`INSERT INTO task_labels (task_id, label_id)
+				SELECT task_id, ? FROM task_labels WHERE label_id = ?
+				ON CONFLICT DO NOTHING`, target.ID, source.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			moved = result.RowsAffected
+
+			if err := tx.Exec("DELETE FROM task_labels WHERE label_id = ?", source.ID).Error; err != nil {
+				return err
+			}
+			return tx.Delete(source).Error
+		})
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Label not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not merge labels", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"label":       target,
+		"tasks_moved": moved,
+	})
+}
+
+func DeleteLabel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	label, role, ok := loadLabel(w, r, userID)
+	if !ok {
+		return
+	}
+	if !canManageWorkspace(role) {
+		http.Error(w, "Only workspace admins can delete labels", http.StatusForbidden)
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		taskIDs, err := labelledTasks(tx, label.ID)
+		if err != nil {
+			return err
+		}
+		return changeTaskLabels(tx, r, taskIDs, func() error {
+			if err := tx.Exec("DELETE FROM task_labels WHERE label_id = ?", label.ID).Error; err != nil {
+				return err
+			}
+			return tx.Delete(label).Error
+		})
+	})
+	if err != nil {
+		http.Error(w, "Could not delete label", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Label deleted successfully"})
+}
+
+// AddTaskLabel puts a label on a task. The caller must be able to edit the
+// task and belong to the label's workspace.
+func AddTaskLabel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var task model.Task
+	if err := database.DB.Scopes(editableTasks(userID)).Where("tasks.id = ?", r.PathValue("id")).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var input model.TaskLabelInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.LabelID == 0 {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	var label model.Label
+	if err := database.DB.First(&label, input.LabelID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Label not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	role, err := workspaceRole(label.WorkspaceID, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if role == "" {
+		http.Error(w, "Label not found", http.StatusNotFound)
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return changeTaskLabels(tx, r, []uint{task.ID}, func() error {
+			return tx.Exec("INSERT INTO task_labels (task_id, label_id) VALUES (?, ?) ON CONFLICT DO NOTHING", task.ID, label.ID).Error
+		})
+	})
+	if err != nil {
+		http.Error(w, "Could not add label", http.StatusInternalServerError)
+		return
+	}
+
+	if err := database.DB.Preload("Labels").Where("id = ?", task.ID).First(&task).Error; err != nil {
+		http.Error(w, "Could not retrieve labels", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", taskETag(task))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"task": task})
+}
+
+func RemoveTaskLabel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var task model.Task
+	if err := database.DB.Scopes(editableTasks(userID)).Where("tasks.id = ?", r.PathValue("id")).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	labelID, err := strconv.ParseUint(r.PathValue("labelId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Label not on task", http.StatusNotFound)
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return changeTaskLabels(tx, r, []uint{task.ID}, func() error {
+			result := tx.Exec("DELETE FROM task_labels WHERE task_id = ? AND label_id = ?", task.ID, labelID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Label not on task", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not remove label", http.StatusInternalServerError)
+		return
+	}
+
+	if err := database.DB.Preload("Labels").Where("id = ?", task.ID).First(&task).Error; err != nil {
+		http.Error(w, "Could not retrieve labels", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", taskETag(task))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"task": task})
+}
@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func createLabel(t *testing.T, user model.User, workspace model.Workspace, body map[string]string) *http.Request {
+	t.Helper()
+	id := strconv.FormatUint(uint64(workspace.ID), 10)
+	r := as(request(t, http.MethodPost, "/api/workspaces/"+id+"/labels", body), user.ID, "session")
+	r.SetPathValue("id", id)
+	return r
+}
+
+// newLabel creates a label in the workspace through the API.
+func newLabel(t *testing.T, user model.User, workspace model.Workspace, name string) model.Label {
+	t.Helper()
+	rec := serve(CreateLabel, createLabel(t, user, workspace, map[string]string{"name": name}))
+	expect(t, rec, http.StatusCreated)
+	var response struct {
+		Label model.Label `json:"label"`
+	}
+	decode(t, rec, &response)
+	return response.Label
+}
+
+// labelRequest builds a request as user for the label named in the path.
+func labelRequest(t *testing.T, method string, user model.User, label model.Label, body interface{}) *http.Request {
+	t.Helper()
+	id := strconv.FormatUint(uint64(label.ID), 10)
+	r := as(request(t, method, "/api/labels/"+id, body), user.ID, "session")
+	r.SetPathValue("id", id)
+	return r
+}
+
+func addLabel(t *testing.T, user model.User, task model.Task, label model.Label) int {
+	t.Helper()
+	return serve(AddTaskLabel, taskRequest(t, http.MethodPost, user, task.ID, map[string]uint{"label_id": label.ID})).Code
+}
+
+func taskVersion(t *testing.T, taskID uint) uint {
+	t.Helper()
+	var task model.Task
+	if err := database.DB.Unscoped().First(&task, taskID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return task.Version
+}
+
+func labelNames(t *testing.T, taskID uint) []string {
+	t.Helper()
+	var names []string
+	database.DB.Raw(`SELECT labels.name FROM task_labels JOIN labels ON labels.id = task_labels.label_id
+		WHERE task_labels.task_id = ? ORDER BY labels.name`, taskID).Scan(&names)
+	return names
+}
+
+func TestCreateLabel(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	outsider := newUser(t, "Bob", "bob@example.com")
+	workspace := newWorkspace(t, owner)
+
+	label := newLabel(t, owner, workspace, " Bug ")
+	if label.Name != "Bug" || label.Color != model.DefaultLabelColor {
+		t.Errorf("label = %+v, want a trimmed name and the default color", label)
+	}
+
+	tests := []struct {
+		name   string
+		user   model.User
+		body   map[string]string
+		status int
+	}{
+		{"name differing in case", owner, map[string]string{"name": "BUG"}, http.StatusConflict},
+		{"bad color", owner, map[string]string{"name": "Feature", "color": "red"}, http.StatusBadRequest},
+		{"empty name", owner, map[string]string{"name": " "}, http.StatusBadRequest},
+		{"outsider", outsider, map[string]string{"name": "Feature"}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rec := serve(CreateLabel, createLabel(t, tt.user, workspace, tt.body)); rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestTaskLabels(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	outsider := newUser(t, "Bob", "bob@example.com")
+	workspace := newWorkspace(t, owner)
+	label := newLabel(t, owner, workspace, "Bug")
+	task := newTask(t, owner, map[string]interface{}{"title": "Crash on start"})
+
+	if status := addLabel(t, owner, task, label); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if version := taskVersion(t, task.ID); version != task.Version+1 {
+		t.Errorf("version = %d, want %d", version, task.Version+1)
+	}
+	var history model.TaskHistory
+	database.DB.Where("task_id = ?", task.ID).Order("id DESC").First(&history)
+	if _, ok := history.Changes["labels"]; !ok {
+		t.Errorf("history = %+v, want a labels change", history)
+	}
+
+	// Adding it again changes nothing.
+	if status := addLabel(t, owner, task, label); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if version := taskVersion(t, task.ID); version != task.Version+1 {
+		t.Errorf("version = %d, want it unchanged at %d", version, task.Version+1)
+	}
+
+	// A label from a workspace the user is not in cannot be used.
+	outsiderTask := newTask(t, outsider, map[string]interface{}{"title": "Elsewhere"})
+	if status := addLabel(t, outsider, outsiderTask, label); status != http.StatusNotFound {
+		t.Errorf("foreign label: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	remove := func() int {
+		r := taskRequest(t, http.MethodDelete, owner, task.ID, nil)
+		r.SetPathValue("labelId", strconv.FormatUint(uint64(label.ID), 10))
+		return serve(RemoveTaskLabel, r).Code
+	}
+	if status := remove(); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if names := labelNames(t, task.ID); len(names) != 0 {
+		t.Errorf("labels = %v, want none", names)
+	}
+	if version := taskVersion(t, task.ID); version != task.Version+2 {
+		t.Errorf("version = %d, want %d", version, task.Version+2)
+	}
+	if status := remove(); status != http.StatusNotFound {
+		t.Errorf("removing twice: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestRenameLabelBumpsTaskVersions(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	member := newUser(t, "Bob", "bob@example.com")
+	workspace := newWorkspace(t, owner)
+	addMember(t, workspace, member, model.WorkspaceRoleMember)
+	bug := newLabel(t, owner, workspace, "Bug")
+	newLabel(t, owner, workspace, "Feature")
+	task := newTask(t, owner, map[string]interface{}{"title": "Crash on start"})
+	addLabel(t, owner, task, bug)
+
+	// Renaming changes the label on every task, so members cannot do it.
+	recolor := map[string]string{"color": "#000000"}
+	expect(t, serve(UpdateLabel, labelRequest(t, http.MethodPatch, member, bug, recolor)), http.StatusForbidden)
+
+	rename := func(name string) int {
+		return serve(UpdateLabel, labelRequest(t, http.MethodPatch, owner, bug, map[string]string{"name": name})).Code
+	}
+	if status := rename("feature"); status != http.StatusConflict {
+		t.Errorf("taken name: status = %d, want %d", status, http.StatusConflict)
+	}
+	if status := rename("Defect"); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if names := labelNames(t, task.ID); len(names) != 1 || names[0] != "Defect" {
+		t.Errorf("labels = %v, want [Defect]", names)
+	}
+	if version := taskVersion(t, task.ID); version != task.Version+2 {
+		t.Errorf("version = %d, want %d", version, task.Version+2)
+	}
+}
+
+func TestLabelChangesOnTrashedTask(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	workspace := newWorkspace(t, owner)
+	bug := newLabel(t, owner, workspace, "Bug")
+	defect := newLabel(t, owner, workspace, "Defect")
+	flaky := newLabel(t, owner, workspace, "Flaky")
+	task := newTask(t, owner, map[string]interface{}{"title": "Crash on start"})
+	addLabel(t, owner, task, bug)
+	addLabel(t, owner, task, flaky)
+	expect(t, serve(DeleteTask, taskRequest(t, http.MethodDelete, owner, task.ID, nil)), http.StatusOK)
+	trashed := taskVersion(t, task.ID)
+
+	// Each change is recorded as a new version of the trashed task.
+	expect(t, serve(UpdateLabel, labelRequest(t, http.MethodPatch, owner, bug, map[string]string{"name": "Crash"})), http.StatusOK)
+	expect(t, serve(MergeLabel, labelRequest(t, http.MethodPost, owner, bug, map[string]uint{"into": defect.ID})), http.StatusOK)
+	expect(t, serve(DeleteLabel, labelRequest(t, http.MethodDelete, owner, flaky, nil)), http.StatusOK)
+	if version := taskVersion(t, task.ID); version != trashed+3 {
+		t.Fatalf("version = %d, want %d", version, trashed+3)
+	}
+
+	expect(t, serve(RestoreTask, taskRequest(t, http.MethodPost, owner, task.ID, nil)), http.StatusOK)
+	if names := labelNames(t, task.ID); len(names) != 1 || names[0] != "Defect" {
+		t.Errorf("labels = %v, want [Defect]", names)
+	}
+}
+
+func TestMergeLabel(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	member := newUser(t, "Bob", "bob@example.com")
+	workspace := newWorkspace(t, owner)
+	addMember(t, workspace, member, model.WorkspaceRoleMember)
+
+	source := newLabel(t, owner, workspace, "Defect")
+	target := newLabel(t, owner, workspace, "Bug")
+	onlySource := newTask(t, owner, map[string]interface{}{"title": "One"})
+	both := newTask(t, owner, map[string]interface{}{"title": "Two"})
+	addLabel(t, owner, onlySource, source)
+	addLabel(t, owner, both, source)
+	addLabel(t, owner, both, target)
+
+	merge := func(user model.User, into uint) *http.Request {
+		return labelRequest(t, http.MethodPost, user, source, map[string]uint{"into": into})
+	}
+	expect(t, serve(MergeLabel, merge(member, target.ID)), http.StatusForbidden)
+	expect(t, serve(MergeLabel, merge(owner, source.ID)), http.StatusBadRequest)
+
+	rec := serve(MergeLabel, merge(owner, target.ID))
+	expect(t, rec, http.StatusOK)
+	var response struct {
+		TasksMoved int64 `json:"tasks_moved"`
+	}
+	decode(t, rec, &response)
+	if response.TasksMoved != 1 {
+		t.Errorf("tasks_moved = %d, want 1", response.TasksMoved)
+	}
+
+	for _, task := range []model.Task{onlySource, both} {
+		if names := labelNames(t, task.ID); len(names) != 1 || names[0] != "Bug" {
+			t.Errorf("task %d labels = %v, want [Bug]", task.ID, names)
+		}
+	}
+	var count int64
+	database.DB.Model(&model.Label{}).Where("id = ?", source.ID).Count(&count)
+	if count != 0 {
+		t.Error("the merged label still exists")
+	}
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/config"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/mailer"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockoutPolicy controls brute-force protection on password login. A zero
+// maximum disables the corresponding check.
+type lockoutPolicy struct {
+	MaxAccountFailures int
+	FailureWindow      time.Duration
+	LockoutDuration    time.Duration
+	MaxLockoutDuration time.Duration
+	MaxIPFailures      int
+	IPWindow           time.Duration
+}
+
+func loadLockoutPolicy() lockoutPolicy {
+	return lockoutPolicy{
+		MaxAccountFailures: config.Int("LOGIN_MAX_FAILED_ATTEMPTS", 5),
+		FailureWindow:      config.Duration("LOGIN_FAILURE_WINDOW", time.Hour),
+		LockoutDuration:    config.Duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		MaxLockoutDuration: config.Duration("LOGIN_LOCKOUT_MAX_DURATION", 24*time.Hour),
+		MaxIPFailures:      config.Int("LOGIN_IP_MAX_FAILED_ATTEMPTS", 20),
+		IPWindow:           config.Duration("LOGIN_IP_WINDOW", 15*time.Minute),
+	}
+}
+
+// lockoutDuration doubles with every consecutive lockout, up to the maximum.
+func (p lockoutPolicy) lockoutDuration(failures int) time.Duration {
+	duration := p.LockoutDuration
+	for level := failures / p.MaxAccountFailures; level > 1; level-- {
+		duration *= 2
+		if duration >= p.MaxLockoutDuration {
+			return p.MaxLockoutDuration
+		}
+	}
+	return duration
+}
+
+func recordLoginAttempt(r *http.Request, userID *uint, email, outcome string) {
+	attempt := model.LoginAttempt{
+		UserID:    userID,
+		Email:     email,
+		IP:        middleware.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		Outcome:   outcome,
+	}
+	if err := database.DB.Create(&attempt).Error; err != nil {
+		log.Printf("Could not record login attempt: %v", err)
+	}
+}
+
+// ipBlocked reports whether the caller's IP exceeded the failed attempt limit
+// within the window.
+func ipBlocked(policy lockoutPolicy, ip string) (bool, error) {
+	if policy.MaxIPFailures <= 0 {
+		return false, nil
+	}
+
+	var failures int64
+	err := database.DB.Model(&model.LoginAttempt{}).
+		Where("ip = ? AND created_at > ? AND outcome IN ?", ip, time.Now().Add(-policy.IPWindow),
+			[]string{model.LoginOutcomeInvalidPassword, model.LoginOutcomeUnknownUser,
+				model.LoginOutcomeLockedOut, model.LoginOutcomeAccountLocked}).
+		Count(&failures).Error
+	if err != nil {
+		return false, err
+	}
+	return failures >= int64(policy.MaxIPFailures), nil
+}
+
+// registerFailedLogin increments the account's failure counter and locks the
+// account when it reaches a multiple of the limit. The counter restarts once
+// the previous failure falls outside the window.
+func registerFailedLogin(policy lockoutPolicy, user *model.User) (locked bool, err error) {
+	now := time.Now()
+
+	err = database.DB.Model(user).Clauses(clause.Returning{}).Updates(map[string]interface{}{
+		"failed_login_attempts": gorm.Expr(
+			"CASE WHEN last_failed_login_at IS NULL OR last_failed_login_at < ? THEN 1 ELSE failed_login_attempts + 1 END",
+			now.Add(-policy.FailureWindow)),
+		"last_failed_login_at": now,
+	}).Error
+	if err != nil {
+		return false, err
+	}
+
+	if policy.MaxAccountFailures <= 0 || user.FailedLoginAttempts%policy.MaxAccountFailures != 0 {
+		return false, nil
+	}
+
+	lockedUntil := now.Add(policy.lockoutDuration(user.FailedLoginAttempts))
+	if err := database.DB.Model(user).Update("locked_until", lockedUntil).Error; err != nil {
+		return false, err
+	}
+	user.LockedUntil = &lockedUntil
+
+	mailer.SendAsync(user.Email, "Your account has been temporarily locked", fmt.Sprintf(
+		"Hi %s,\n\nWe locked your account until %s UTC after %d failed sign-in attempts.\n"+
+			"If this wasn't you, consider changing your password once the lock expires.",
+		user.Name, lockedUntil.UTC().Format(time.RFC1123), user.FailedLoginAttempts))
+	log.Printf("Locked user %d until %s after %d failed logins", user.ID, lockedUntil.Format(time.RFC3339), user.FailedLoginAttempts)
+	return true, nil
+}
+
+func resetFailedLogins(user *model.User) {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return
+	}
+	err := database.DB.Model(user).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  nil,
+		"locked_until":          nil,
+	}).Error
+	if err != nil {
+		log.Printf("Could not reset failed logins for user %d: %v", user.ID, err)
+	}
+}
+
+func writeTooManyAttempts(w http.ResponseWriter, message string, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+	http.Error(w, message, http.StatusTooManyRequests)
+}
@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	policy := lockoutPolicy{
+		MaxAccountFailures: 5,
+		LockoutDuration:    15 * time.Minute,
+		MaxLockoutDuration: 2 * time.Hour,
+	}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{5, 15 * time.Minute},
+		{9, 15 * time.Minute},
+		{10, 30 * time.Minute},
+		{15, time.Hour},
+		{20, 2 * time.Hour},
+		{25, 2 * time.Hour},
+		{500, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := policy.lockoutDuration(tt.failures); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoadLockoutPolicy(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILED_ATTEMPTS", "3")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "1m")
+	t.Setenv("LOGIN_IP_WINDOW", "not a duration")
+
+	policy := loadLockoutPolicy()
+	if policy.MaxAccountFailures != 3 {
+		t.Errorf("MaxAccountFailures = %d, want 3", policy.MaxAccountFailures)
+	}
+	if policy.LockoutDuration != time.Minute {
+		t.Errorf("LockoutDuration = %v, want 1m", policy.LockoutDuration)
+	}
+	if policy.IPWindow != 15*time.Minute {
+		t.Errorf("IPWindow = %v, want the 15m default", policy.IPWindow)
+	}
+	if policy.MaxIPFailures != 20 {
+		t.Errorf("MaxIPFailures = %d, want the default 20", policy.MaxIPFailures)
+	}
+}
+
+func TestLoginUserBlockedAccountKeepsFailures(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	hash, err := middleware.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := newUser(t, "Ada", "ada@example.com")
+	database.DB.Model(&user).Updates(map[string]interface{}{"password": hash, "failed_login_attempts": 3})
+
+	login := func() int {
+		body := map[string]string{"email": "ada@example.com", "password": "correct horse"}
+		return serve(LoginUser, request(t, http.MethodPost, "/api/auth/login", body)).Code
+	}
+	lastOutcome := func() string {
+		var attempt model.LoginAttempt
+		database.DB.Where("user_id = ?", user.ID).Order("id DESC").First(&attempt)
+		return attempt.Outcome
+	}
+	failures := func() int {
+		var stored model.User
+		database.DB.First(&stored, user.ID)
+		return stored.FailedLoginAttempts
+	}
+
+	database.DB.Model(&user).Update("disabled_at", time.Now())
+	if status := login(); status != http.StatusForbidden {
+		t.Errorf("disabled account: status = %d, want %d", status, http.StatusForbidden)
+	}
+	if outcome := lastOutcome(); outcome != model.LoginOutcomeAccountDisabled {
+		t.Errorf("disabled account: outcome = %q", outcome)
+	}
+	if n := failures(); n != 3 {
+		t.Errorf("disabled account: failed attempts = %d, want them kept at 3", n)
+	}
+
+	database.DB.Model(&user).Updates(map[string]interface{}{"disabled_at": nil, "password_reset_required": true})
+	if status := login(); status != http.StatusForbidden {
+		t.Errorf("reset required: status = %d, want %d", status, http.StatusForbidden)
+	}
+	if outcome := lastOutcome(); outcome != model.LoginOutcomePasswordResetRequired {
+		t.Errorf("reset required: outcome = %q", outcome)
+	}
+	if n := failures(); n != 3 {
+		t.Errorf("reset required: failed attempts = %d, want them kept at 3", n)
+	}
+
+	database.DB.Model(&user).Update("password_reset_required", false)
+	if status := login(); status != http.StatusOK {
+		t.Errorf("active account: status = %d, want %d", status, http.StatusOK)
+	}
+	if outcome := lastOutcome(); outcome != model.LoginOutcomeSuccess {
+		t.Errorf("active account: outcome = %q", outcome)
+	}
+	if n := failures(); n != 0 {
+		t.Errorf("active account: failed attempts = %d, want 0", n)
+	}
+}
+
+func TestLoginLockoutDoesNotRevealAccounts(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("LOGIN_MAX_FAILED_ATTEMPTS", "2")
+	t.Setenv("LOGIN_IP_MAX_FAILED_ATTEMPTS", "0")
+
+	hash, err := middleware.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := newUser(t, "Ada", "ada@example.com")
+	database.DB.Model(&user).Update("password", hash)
+
+	login := func(email, password string) *httptest.ResponseRecorder {
+		body := map[string]string{"email": email, "password": password}
+		return serve(LoginUser, request(t, http.MethodPost, "/api/auth/login", body))
+	}
+	unknown := login("nobody@example.com", "correct horse")
+	expect(t, unknown, http.StatusUnauthorized)
+
+	// The failure that locks the account, and every attempt while it is
+	// locked, even with the right password, look like any other failure.
+	for i, password := range []string{"wrong", "wrong", "correct horse"} {
+		rec := login("ada@example.com", password)
+		if rec.Code != unknown.Code || rec.Body.String() != unknown.Body.String() || rec.Header().Get("Retry-After") != "" {
+			t.Errorf("attempt %d: %d %q, want the same answer as for an unknown account", i+1, rec.Code, rec.Body.String())
+		}
+	}
+
+	var stored model.User
+	database.DB.First(&stored, user.ID)
+	if stored.LockedUntil == nil {
+		t.Fatal("the account was not locked")
+	}
+}
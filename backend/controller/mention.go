@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm/clause"
+)
+
+// mentionPattern matches @handle where the @ does not follow a word
+// character, so email addresses are not taken for mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([\p{L}\p{N}._-]+)`)
+
+// mentionHandles returns the distinct handles mentioned in text, lowercased.
+func mentionHandles(text string) []string {
+	var handles []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		handle := strings.ToLower(strings.TrimRight(match[1], ".-_"))
+		if handle != "" && !seen[handle] {
+			seen[handle] = true
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+// resolveMention finds the one candidate a handle refers to, by user ID, the
+// local part of their email address or their name without spaces. Unknown
+// and ambiguous handles do not resolve.
+func resolveMention(handle string, candidates []model.User) (model.User, bool) {
+	var matches []model.User
+	for _, user := range candidates {
+		localPart, _, _ := strings.Cut(strings.ToLower(user.Email), "@")
+		compactName := strings.ToLower(strings.Join(strings.Fields(user.Name), ""))
+		if handle == strconv.FormatUint(uint64(user.ID), 10) || handle == localPart || handle == compactName {
+			matches = append(matches, user)
+		}
+	}
+	if len(matches) != 1 {
+		return model.User{}, false
+	}
+	return matches[0], true
+}
+
+// taskAudience loads the users who can currently see the task.
+func taskAudience(task *model.Task) ([]model.User, error) {
+	var granted []uint
+	if err := database.DB.Model(&model.TaskAccess{}).Where("task_id = ?", task.ID).Pluck("user_id", &granted).Error; err != nil {
+		return nil, err
+	}
+	var watchers []uint
+	if err := database.DB.Model(&model.TaskWatcher{}).Where("task_id = ?", task.ID).Pluck("user_id", &watchers).Error; err != nil {
+		return nil, err
+	}
+	granted = append(granted, watchers...)
+
+	var users []model.User
+	err := database.DB.Select("id", "name", "email").
+		Where("id IN ?", append(granted, task.CreatedBy, task.AssignedTo)).
+		Find(&users).Error
+	return users, err
+}
+
+// recordMentions notifies the users mentioned in after but not in before and
+// grants them lasting read access to the task. Handles are only resolved
+// against people who can see the task, so a mention never reveals it to an
+// outsider or confirms that an account exists. It returns the users it
+// notified.
+func recordMentions(task *model.Task, actorID uint, before, after string, commentID *uint) map[uint]bool {
+	previous := map[string]bool{}
+	for _, handle := range mentionHandles(before) {
+		previous[handle] = true
+	}
+	var handles []string
+	for _, handle := range mentionHandles(after) {
+		if !previous[handle] {
+			handles = append(handles, handle)
+		}
+	}
+	if len(handles) == 0 {
+		return nil
+	}
+
+	audience, err := taskAudience(task)
+	if err != nil {
+		log.Printf("Could not resolve mentions on task %d: %v", task.ID, err)
+		return nil
+	}
+
+	actorName := "Someone"
+	for _, user := range audience {
+		if user.ID == actorID {
+			actorName = user.Name
+		}
+	}
+
+	where := "the description of"
+	if commentID != nil {
+		where = "a comment on"
+	}
+
+	notified := map[uint]bool{actorID: true}
+	for _, handle := range handles {
+		user, ok := resolveMention(handle, audience)
+		if !ok || notified[user.ID] {
+			continue
+		}
+		notified[user.ID] = true
+
+		if user.ID != task.CreatedBy && user.ID != task.AssignedTo {
+			err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.TaskAccess{
+				TaskID:    task.ID,
+				UserID:    user.ID,
+				Reason:    model.TaskAccessMention,
+				GrantedBy: actorID,
+			}).Error
+			if err != nil {
+				log.Printf("Could not grant user %d access to task %d: %v", user.ID, task.ID, err)
+			}
+		}
+
+		taskID := task.ID
+		notify(model.Notification{
+			UserID:    user.ID,
+			Type:      model.NotificationMention,
+			TaskID:    &taskID,
+			CommentID: commentID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("%s mentioned you in %s %q", actorName, where, task.Title),
+		})
+	}
+	return notified
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestMentionHandles(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"no mentions here", nil},
+		{"@alice please review", []string{"alice"}},
+		{"cc @Alice and @bob.smith, thanks @ALICE.", []string{"alice", "bob.smith"}},
+		{"mail alice@example.com", nil},
+		{"(@carol) @dave- @12", []string{"carol", "dave", "12"}},
+		{"@@eve word@frank _@gina", nil},
+		{"@josé", []string{"josé"}},
+		{"@...", nil},
+	}
+	for _, tt := range tests {
+		if got := mentionHandles(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mentionHandles(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestResolveMention(t *testing.T) {
+	candidates := []model.User{
+		{ID: 1, Name: "Alice Smith", Email: "alice@example.com"},
+		{ID: 2, Name: "Bob", Email: "bob.jones@example.com"},
+		{ID: 3, Name: "Bob", Email: "robert@example.com"},
+		{ID: 4, Name: "Carol", Email: "Carol.K@Example.com"},
+	}
+	tests := []struct {
+		handle string
+		want   uint
+		ok     bool
+	}{
+		{"alice", 1, true},
+		{"alicesmith", 1, true},
+		{"1", 1, true},
+		{"bob.jones", 2, true},
+		{"bob", 0, false},
+		{"carol.k", 4, true},
+		{"carol", 4, true},
+		{"dave", 0, false},
+		{"99", 0, false},
+	}
+	for _, tt := range tests {
+		user, ok := resolveMention(tt.handle, candidates)
+		if ok != tt.ok || user.ID != tt.want {
+			t.Errorf("resolveMention(%q) = user %d, %t, want user %d, %t", tt.handle, user.ID, ok, tt.want, tt.ok)
+		}
+	}
+}
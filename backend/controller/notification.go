@@ -0,0 +1,310 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/config"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm/clause"
+)
+
+// userName looks up a user's display name for notification messages.
+func userName(userID uint) string {
+	var user model.User
+	if err := database.DB.Select("id", "name").First(&user, userID).Error; err != nil || user.Name == "" {
+		return "Someone"
+	}
+	return user.Name
+}
+
+// notifyTaskChanges tells the new assignee about an assignment and the
+// task's watchers about a status change. before is nil for new tasks.
+func notifyTaskChanges(task *model.Task, before *model.Task, actorID uint) {
+	taskID := task.ID
+	assigned := task.AssignedTo != 0 && task.AssignedTo != actorID &&
+		(before == nil || before.AssignedTo != task.AssignedTo)
+	statusChanged := before != nil && before.Status != task.Status
+	if !assigned && !statusChanged {
+		return
+	}
+
+	actorName := userName(actorID)
+
+	if assigned {
+		notify(model.Notification{
+			UserID:  task.AssignedTo,
+			Type:    model.NotificationAssigned,
+			TaskID:  &taskID,
+			ActorID: actorID,
+			Message: fmt.Sprintf("%s assigned you %q", actorName, task.Title),
+		})
+	}
+
+	if statusChanged {
+		notified := map[uint]bool{actorID: true, 0: true}
+		for _, userID := range taskWatchers(task) {
+			if notified[userID] {
+				continue
+			}
+			notified[userID] = true
+			notify(model.Notification{
+				UserID:  userID,
+				Type:    model.NotificationStatusChanged,
+				TaskID:  &taskID,
+				ActorID: actorID,
+				Message: fmt.Sprintf("%s changed the status of %q to %s", actorName, task.Title, task.Status),
+			})
+		}
+	}
+}
+
+// notifyComment tells a task's watchers about a new comment, skipping
+// its author and anyone already notified of a mention in it.
+func notifyComment(task *model.Task, comment *model.Comment, skip map[uint]bool) {
+	taskID, commentID := task.ID, comment.ID
+	actorName := userName(comment.AuthorID)
+
+	notified := map[uint]bool{comment.AuthorID: true, 0: true}
+	for userID := range skip {
+		notified[userID] = true
+	}
+	for _, userID := range taskWatchers(task) {
+		if notified[userID] {
+			continue
+		}
+		notified[userID] = true
+		notify(model.Notification{
+			UserID:    userID,
+			Type:      model.NotificationComment,
+			TaskID:    &taskID,
+			CommentID: &commentID,
+			ActorID:   comment.AuthorID,
+			Message:   fmt.Sprintf("%s commented on %q", actorName, task.Title),
+		})
+	}
+}
+
+func dueSoonWindow() time.Duration {
+	return config.Duration("NOTIFY_DUE_SOON_WINDOW", 24*time.Hour)
+}
+
+// NotifyDueSoonTasks reminds assignees of open tasks that fall due within the
+// window. Each task is announced once per due date.
+func NotifyDueSoonTasks() {
+	now := time.Now()
+
+	var tasks []model.Task
+	err := database.DB.
+		Where("due_date > ? AND due_date <= ? AND status <> ? AND assigned_to <> 0", now, now.Add(dueSoonWindow()), "completed").
+		Where("NOT EXISTS (SELECT 1 FROM due_soon_reminders WHERE due_soon_reminders.task_id = tasks.id " +
+			"AND due_soon_reminders.user_id = tasks.assigned_to AND due_soon_reminders.due_date = tasks.due_date)").
+		Find(&tasks).Error
+	if err != nil {
+		log.Printf("[Notify] Could not load tasks due soon: %v", err)
+		return
+	}
+
+	for _, task := range tasks {
+		// Claiming the reminder first keeps concurrent runs from sending it twice.
+		result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.DueSoonReminder{
+			TaskID:  task.ID,
+			UserID:  task.AssignedTo,
+			DueDate: *task.DueDate,
+		})
+		if result.Error != nil {
+			log.Printf("[Notify] Could not record reminder for task %d: %v", task.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		taskID := task.ID
+		notify(model.Notification{
+			UserID:  task.AssignedTo,
+			Type:    model.NotificationDueSoon,
+			TaskID:  &taskID,
+			Message: fmt.Sprintf("%q is due %s", task.Title, task.DueDate.UTC().Format(time.RFC1123)),
+		})
+	}
+}
+
+// GetNotifications lists the caller's notifications, newest first, with the
+// number still unread. Pass unread=true to only list unread ones.
+func GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit, _ := parsePagination(r, 50, 200)
+
+	query := database.DB.Where("user_id = ?", userID)
+	if r.URL.Query().Get("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		before, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("id < ?", before)
+	}
+
+	var notifications []model.Notification
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&notifications).Error; err != nil {
+		http.Error(w, "Could not retrieve notifications", http.StatusInternalServerError)
+		return
+	}
+
+	var nextCursor string
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		nextCursor = strconv.FormatUint(uint64(notifications[limit-1].ID), 10)
+	}
+
+	var unread int64
+	if err := database.DB.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		http.Error(w, "Could not retrieve notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"notifications": notifications,
+		"unread_count":  unread,
+		"next_cursor":   nextCursor,
+	})
+}
+
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var notification model.Notification
+	if err := database.DB.Where("id = ? AND user_id = ?", r.PathValue("id"), userID).First(&notification).Error; err != nil {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := database.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			http.Error(w, "Could not update notification", http.StatusInternalServerError)
+			return
+		}
+		notification.ReadAt = &now
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"notification": notification})
+}
+
+func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result := database.DB.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		http.Error(w, "Could not update notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"marked_read": result.RowsAffected})
+}
+
+func GetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var user model.User
+	if err := database.DB.Select("id", "preferences").First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"settings": user.Preferences.Notifications,
+		"types":    model.NotificationTypes,
+	})
+}
+
+// UpdateNotificationSettings changes how notifications reach the caller.
+// Channels are merged per event type; quiet hours are replaced as a whole.
+func UpdateNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input model.NotificationSettingsInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	settings := user.Preferences.Notifications
+	channels := make(map[string]string, len(settings.Channels)+len(input.Channels))
+	for eventType, channel := range settings.Channels {
+		channels[eventType] = channel
+	}
+	for eventType, channel := range input.Channels {
+		channels[eventType] = channel
+	}
+	settings.Channels = channels
+	if input.WebhookURL != nil {
+		settings.WebhookURL = strings.TrimSpace(*input.WebhookURL)
+	}
+	if input.ClearQuietHours {
+		settings.QuietHours = nil
+	} else if input.QuietHours != nil {
+		settings.QuietHours = input.QuietHours
+	}
+
+	if msg := validateNotificationSettings(settings); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	user.Preferences.Notifications = settings
+	if err := database.DB.Select("Preferences").Save(&user).Error; err != nil {
+		http.Error(w, "Could not update notification settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"settings": user.Preferences.Notifications,
+		"types":    model.NotificationTypes,
+	})
+}
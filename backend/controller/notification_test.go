@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+// notificationsOf returns the types of the user's notifications, oldest first.
+func notificationsOf(t *testing.T, user model.User) []string {
+	t.Helper()
+	var types []string
+	database.DB.Model(&model.Notification{}).Where("user_id = ?", user.ID).Order("id").Pluck("type", &types)
+	return types
+}
+
+type inbox struct {
+	Notifications []model.Notification `json:"notifications"`
+	UnreadCount   int64                `json:"unread_count"`
+	NextCursor    string               `json:"next_cursor"`
+}
+
+func getInbox(t *testing.T, user model.User, query string) inbox {
+	t.Helper()
+	rec := serve(GetNotifications, as(request(t, http.MethodGet, "/api/notifications?"+query, nil), user.ID, "session"))
+	expect(t, rec, http.StatusOK)
+	var response inbox
+	decode(t, rec, &response)
+	return response
+}
+
+func TestTaskChangesNotify(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	assignee := newUser(t, "Bob", "bob@example.com")
+	addMember(t, newWorkspace(t, owner), assignee, model.WorkspaceRoleMember)
+
+	newTask(t, owner, map[string]interface{}{"title": "Mine"})
+	task := newTask(t, owner, map[string]interface{}{"title": "Yours", "assigned_to": assignee.ID})
+	if types := notificationsOf(t, assignee); len(types) != 1 || types[0] != model.NotificationAssigned {
+		t.Fatalf("assignee notifications = %v, want [assigned]", types)
+	}
+
+	patch := map[string]interface{}{"status": "completed"}
+	expect(t, serve(PatchTask, taskRequest(t, http.MethodPatch, owner, task.ID, patch)), http.StatusOK)
+
+	if types := notificationsOf(t, assignee); len(types) != 2 || types[1] != model.NotificationStatusChanged {
+		t.Errorf("assignee notifications = %v, want a status change last", types)
+	}
+	if types := notificationsOf(t, owner); len(types) != 0 {
+		t.Errorf("the actor was notified of their own changes: %v", types)
+	}
+}
+
+func TestGetNotifications(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+	other := newUser(t, "Bob", "bob@example.com")
+
+	var ids []uint
+	for i := 0; i < 3; i++ {
+		notification := model.Notification{UserID: user.ID, Type: model.NotificationComment, Message: strconv.Itoa(i)}
+		create(t, &notification)
+		ids = append(ids, notification.ID)
+	}
+	create(t, &model.Notification{UserID: other.ID, Type: model.NotificationComment})
+
+	page := getInbox(t, user, "limit=2")
+	if len(page.Notifications) != 2 || page.Notifications[0].ID != ids[2] || page.UnreadCount != 3 || page.NextCursor == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+	page = getInbox(t, user, "limit=2&cursor="+page.NextCursor)
+	if len(page.Notifications) != 1 || page.Notifications[0].ID != ids[0] || page.NextCursor != "" {
+		t.Fatalf("second page = %+v", page)
+	}
+
+	markRead := func(reader model.User, id uint) int {
+		r := as(request(t, http.MethodPost, "/api/notifications/"+strconv.FormatUint(uint64(id), 10)+"/read", nil), reader.ID, "session")
+		r.SetPathValue("id", strconv.FormatUint(uint64(id), 10))
+		return serve(MarkNotificationRead, r).Code
+	}
+	if status := markRead(other, ids[1]); status != http.StatusNotFound {
+		t.Errorf("another user's notification: status = %d, want %d", status, http.StatusNotFound)
+	}
+	if status := markRead(user, ids[1]); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	unread := getInbox(t, user, "unread=true")
+	if len(unread.Notifications) != 2 || unread.UnreadCount != 2 {
+		t.Errorf("unread = %+v, want 2", unread)
+	}
+
+	rec := serve(MarkAllNotificationsRead, as(request(t, http.MethodPost, "/api/notifications/read-all", nil), user.ID, "session"))
+	expect(t, rec, http.StatusOK)
+	var marked struct {
+		MarkedRead int64 `json:"marked_read"`
+	}
+	decode(t, rec, &marked)
+	if marked.MarkedRead != 2 {
+		t.Errorf("marked_read = %d, want 2", marked.MarkedRead)
+	}
+	if page := getInbox(t, other, ""); page.UnreadCount != 1 {
+		t.Errorf("another user's unread count = %d, want it untouched", page.UnreadCount)
+	}
+}
+
+func TestNotifyDueSoonTasksOnce(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	assignee := newUser(t, "Bob", "bob@example.com")
+
+	due := time.Now().Add(time.Hour)
+	later := time.Now().Add(72 * time.Hour)
+	create(t,
+		&model.Task{Title: "Soon", Status: "pending", DueDate: &due, AssignedTo: assignee.ID, CreatedBy: owner.ID},
+		&model.Task{Title: "Done", Status: "completed", DueDate: &due, AssignedTo: assignee.ID, CreatedBy: owner.ID},
+		&model.Task{Title: "Later", Status: "pending", DueDate: &later, AssignedTo: assignee.ID, CreatedBy: owner.ID},
+	)
+
+	NotifyDueSoonTasks()
+	NotifyDueSoonTasks()
+
+	if types := notificationsOf(t, assignee); len(types) != 1 || types[0] != model.NotificationDueSoon {
+		t.Errorf("notifications = %v, want a single due soon reminder", types)
+	}
+}
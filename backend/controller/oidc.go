@@ -0,0 +1,319 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const oidcAuthRequestTTL = 10 * time.Minute
+
+// oidcStateCookie binds a login to the browser that started it, so a callback
+// for someone else's login cannot sign the browser into their account.
+const oidcStateCookie = "oidc_state"
+
+var (
+	errOIDCEmailConflict   = errors.New("email belongs to an existing account but is not verified by both the identity provider and the account")
+	errOIDCEmailUnverified = errors.New("email is not verified by the identity provider")
+)
+
+type oidcClient struct {
+	config   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	issuer   string
+}
+
+var (
+	oidcMu     sync.Mutex
+	oidcCached *oidcClient
+)
+
+// getOIDCClient runs issuer discovery on first use and caches the result.
+// A failed discovery is retried on the next request.
+func getOIDCClient(ctx context.Context) (*oidcClient, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+
+	if oidcCached != nil {
+		return oidcCached, nil
+	}
+
+	issuer := os.Getenv("OIDC_ISSUER_URL")
+	clientID := os.Getenv("OIDC_CLIENT_ID")
+	if issuer == "" || clientID == "" {
+		return nil, errors.New("OIDC is not configured")
+	}
+
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []string{oidc.ScopeOpenID, "profile", "email"}
+	if extra := os.Getenv("OIDC_SCOPES"); extra != "" {
+		scopes = append([]string{oidc.ScopeOpenID}, strings.Fields(extra)...)
+	}
+
+	oidcCached = &oidcClient{
+		config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+		issuer:   issuer,
+	}
+	return oidcCached, nil
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	client, err := getOIDCClient(r.Context())
+	if err != nil {
+		log.Printf("[OIDC] Discovery failed: %v", err)
+		http.Error(w, "Single sign-on is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	state, err := randomString(16)
+	if err != nil {
+		http.Error(w, "Could not start login", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := randomString(16)
+	if err != nil {
+		http.Error(w, "Could not start login", http.StatusInternalServerError)
+		return
+	}
+
+	// Abandoned logins are cleaned up opportunistically.
+	database.DB.Where("expires_at < ?", time.Now()).Delete(&model.OIDCAuthRequest{})
+
+	authRequest := model.OIDCAuthRequest{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		ExpiresAt:    time.Now().Add(oidcAuthRequestTTL),
+	}
+	if err := database.DB.Create(&authRequest).Error; err != nil {
+		http.Error(w, "Could not start login", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    middleware.HashToken(state),
+		Path:     "/api/auth/oidc",
+		MaxAge:   int(oidcAuthRequestTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(client.config.RedirectURL, "https://"),
+		// Lax still sends the cookie on the provider's top-level redirect back.
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	authURL := client.config.AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(authRequest.CodeVerifier),
+	)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if errParam := r.URL.Query().Get("error"); errParam != "" {
+		log.Printf("[OIDC] Provider returned error: %s %s", errParam, r.URL.Query().Get("error_description"))
+		http.Error(w, "Single sign-on failed", http.StatusUnauthorized)
+		return
+	}
+
+	state := r.URL.Query().Get("state")
+	code := r.URL.Query().Get("code")
+	if state == "" || code == "" {
+		http.Error(w, "Missing state or code", http.StatusBadRequest)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(middleware.HashToken(state))) != 1 {
+		http.Error(w, "Login was not started in this browser", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/auth/oidc", MaxAge: -1})
+
+	// Each state can only be redeemed once.
+	var authRequest model.OIDCAuthRequest
+	result := database.DB.Clauses(clause.Returning{}).Where("state = ?", state).Delete(&authRequest)
+	if result.Error != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 || authRequest.ExpiresAt.Before(time.Now()) {
+		http.Error(w, "Invalid or expired login request", http.StatusBadRequest)
+		return
+	}
+
+	client, err := getOIDCClient(r.Context())
+	if err != nil {
+		log.Printf("[OIDC] Discovery failed: %v", err)
+		http.Error(w, "Single sign-on is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	oauthToken, err := client.config.Exchange(r.Context(), code, oauth2.VerifierOption(authRequest.CodeVerifier))
+	if err != nil {
+		log.Printf("[OIDC] Code exchange failed: %v", err)
+		http.Error(w, "Single sign-on failed", http.StatusUnauthorized)
+		return
+	}
+
+	rawIDToken, ok := oauthToken.Extra("id_token").(string)
+	if !ok {
+		http.Error(w, "Provider did not return an ID token", http.StatusUnauthorized)
+		return
+	}
+
+	idToken, err := client.verifier.Verify(r.Context(), rawIDToken)
+	if err != nil {
+		log.Printf("[OIDC] ID token verification failed: %v", err)
+		http.Error(w, "Single sign-on failed", http.StatusUnauthorized)
+		return
+	}
+	if idToken.Nonce != authRequest.Nonce {
+		http.Error(w, "Single sign-on failed", http.StatusUnauthorized)
+		return
+	}
+
+	var claims struct {
+		Email         string      `json:"email"`
+		EmailVerified interface{} `json:"email_verified"`
+		Name          string      `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		http.Error(w, "Invalid ID token claims", http.StatusUnauthorized)
+		return
+	}
+
+	// Some providers send email_verified as a string.
+	emailVerified := claims.EmailVerified == true || claims.EmailVerified == "true"
+
+	user, err := findOrProvisionOIDCUser(client.issuer, idToken.Subject, claims.Email, emailVerified, claims.Name)
+	if err != nil {
+		if errors.Is(err, errOIDCEmailConflict) {
+			http.Error(w, "An account with this email already exists", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errOIDCEmailUnverified) {
+			http.Error(w, "Your identity provider has not verified your email address", http.StatusForbidden)
+			return
+		}
+		log.Printf("[OIDC] Provisioning failed: %v", err)
+		http.Error(w, "Could not sign in", http.StatusInternalServerError)
+		return
+	}
+
+	if user.DisabledAt != nil {
+		http.Error(w, "Account disabled", http.StatusForbidden)
+		return
+	}
+
+	token, err := startSession(r, user.ID)
+	if err != nil {
+		http.Error(w, "Could not generate token", http.StatusInternalServerError)
+		return
+	}
+
+	// Browser flows hand the token back to the frontend in the URL fragment so
+	// it never reaches server logs.
+	if redirect := os.Getenv("OIDC_POST_LOGIN_REDIRECT_URL"); redirect != "" {
+		http.Redirect(w, r, redirect+"#token="+url.QueryEscape(token), http.StatusFound)
+		return
+	}
+
+	writeAuthResponse(w, http.StatusOK, *user, token, nil)
+}
+
+// findOrProvisionOIDCUser resolves the local user for an OIDC identity. Known
+// identities map straight to their user, verified emails link to an existing
+// account or are provisioned just in time. An unverified email is never
+// linked or claimed for a new account, and neither is an account that has not
+// verified its own email, since whoever registered it may not own the address.
+func findOrProvisionOIDCUser(issuer, subject, email string, emailVerified bool, name string) (*model.User, error) {
+	var user model.User
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var identity model.OIDCIdentity
+		err := tx.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+		if err == nil {
+			return tx.First(&user, identity.UserID).Error
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		if email == "" {
+			return errors.New("ID token has no email claim")
+		}
+
+		err = tx.Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+		switch {
+		case err == nil:
+			if !emailVerified || !user.EmailVerified {
+				return errOIDCEmailConflict
+			}
+		case err == gorm.ErrRecordNotFound:
+			if !emailVerified {
+				return errOIDCEmailUnverified
+			}
+			if name = cleanName(name); name == "" {
+				name = email
+			}
+			user = model.User{
+				Name:          name,
+				Email:         email,
+				EmailVerified: true,
+				Preferences:   model.DefaultPreferences(),
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return tx.Create(&model.OIDCIdentity{
+			UserID:  user.ID,
+			Issuer:  issuer,
+			Subject: subject,
+			Email:   email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,376 @@
+package controller
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+const oidcTestClientID = "zocket"
+
+// fakeIdP is a minimal OpenID provider. Codes are issued by authorize and
+// redeemed at the token endpoint, which checks the PKCE verifier.
+type fakeIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]fakeGrant
+}
+
+type fakeGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIdP{key: key, codes: map[string]fakeGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	t.Setenv("OIDC_ISSUER_URL", idp.server.URL)
+	t.Setenv("OIDC_CLIENT_ID", oidcTestClientID)
+	t.Setenv("OIDC_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_REDIRECT_URL", "http://localhost/api/auth/oidc/callback")
+	t.Setenv("OIDC_SCOPES", "")
+	t.Setenv("OIDC_POST_LOGIN_REDIRECT_URL", "")
+	t.Setenv("JWT_SECRET", "test-secret")
+	resetOIDCClient(t)
+	return idp
+}
+
+// resetOIDCClient forgets the cached discovery now and when the test ends.
+func resetOIDCClient(t *testing.T) {
+	oidcMu.Lock()
+	oidcCached = nil
+	oidcMu.Unlock()
+	t.Cleanup(func() {
+		oidcMu.Lock()
+		oidcCached = nil
+		oidcMu.Unlock()
+	})
+}
+
+// authorize plays the user approving the login the app redirected to, and
+// returns the code the provider would send back.
+func (idp *fakeIdP) authorize(t *testing.T, authURL string, subject, email string, emailVerified interface{}) string {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization request does not use PKCE: %s", authURL)
+	}
+
+	now := time.Now()
+	code := randomTestString(t)
+	idp.mu.Lock()
+	idp.codes[code] = fakeGrant{
+		challenge: query.Get("code_challenge"),
+		claims: jwt.MapClaims{
+			"iss":            idp.server.URL,
+			"aud":            oidcTestClientID,
+			"sub":            subject,
+			"iat":            now.Unix(),
+			"exp":            now.Add(time.Hour).Unix(),
+			"nonce":          query.Get("nonce"),
+			"email":          email,
+			"email_verified": emailVerified,
+			"name":           "Ada Lovelace",
+		},
+	}
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.FormValue("code")]
+	delete(idp.codes, r.FormValue("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+	idToken.Header["kid"] = "test"
+	signed, err := idToken.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+func randomTestString(t *testing.T) string {
+	t.Helper()
+	s, err := randomString(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// startOIDCLogin runs OIDCLogin and returns the provider URL it redirects to
+// along with the state cookie it set.
+func startOIDCLogin(t *testing.T) (string, *http.Cookie) {
+	t.Helper()
+	rec := serve(OIDCLogin, request(t, http.MethodGet, "/api/auth/oidc/login", nil))
+	expect(t, rec, http.StatusFound)
+
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == oidcStateCookie {
+			if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+				t.Errorf("state cookie is not HttpOnly and SameSite=Lax: %+v", cookie)
+			}
+			return rec.Header().Get("Location"), cookie
+		}
+	}
+	t.Fatal("OIDCLogin did not set the state cookie")
+	return "", nil
+}
+
+// finishOIDCLogin calls back with code for the login started at authURL.
+func finishOIDCLogin(t *testing.T, authURL, code string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	parsed, _ := url.Parse(authURL)
+	query := url.Values{"state": {parsed.Query().Get("state")}, "code": {code}}
+	r := request(t, http.MethodGet, "/api/auth/oidc/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	return serve(OIDCCallback, r)
+}
+
+func TestGetOIDCClientDiscovery(t *testing.T) {
+	idp := newFakeIdP(t)
+
+	client, err := getOIDCClient(t.Context())
+	if err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	if client.config.Endpoint.AuthURL != idp.server.URL+"/authorize" || client.config.Endpoint.TokenURL != idp.server.URL+"/token" {
+		t.Errorf("endpoints = %+v", client.config.Endpoint)
+	}
+	if len(client.config.Scopes) != 3 {
+		t.Errorf("scopes = %v, want openid profile email", client.config.Scopes)
+	}
+
+	again, _ := getOIDCClient(t.Context())
+	if again != client {
+		t.Error("discovery result is not cached")
+	}
+}
+
+func TestOIDCLoginUnavailable(t *testing.T) {
+	resetOIDCClient(t)
+	idp := httptest.NewServer(http.NotFoundHandler())
+	defer idp.Close()
+	t.Setenv("OIDC_ISSUER_URL", idp.URL)
+	t.Setenv("OIDC_CLIENT_ID", oidcTestClientID)
+
+	rec := serve(OIDCLogin, request(t, http.MethodGet, "/api/auth/oidc/login", nil))
+	expect(t, rec, http.StatusServiceUnavailable)
+
+	t.Setenv("OIDC_ISSUER_URL", "")
+	rec = serve(OIDCLogin, request(t, http.MethodGet, "/api/auth/oidc/login", nil))
+	expect(t, rec, http.StatusServiceUnavailable)
+}
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	state := "0123456789abcdef"
+	target := "/api/auth/oidc/callback?state=" + state + "&code=abc"
+
+	rec := serve(OIDCCallback, request(t, http.MethodGet, target, nil))
+	expect(t, rec, http.StatusBadRequest)
+
+	r := request(t, http.MethodGet, target, nil)
+	r.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "someone else's login"})
+	expect(t, serve(OIDCCallback, r), http.StatusBadRequest)
+}
+
+func TestOIDCLoginProvisionsVerifiedUser(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIdP(t)
+
+	authURL, cookie := startOIDCLogin(t)
+	rec := finishOIDCLogin(t, authURL, idp.authorize(t, authURL, "subject-1", "ada@example.com", true), cookie)
+	expect(t, rec, http.StatusOK)
+
+	var response struct {
+		User  struct{ ID uint } `json:"user"`
+		Token string            `json:"token"`
+	}
+	decode(t, rec, &response)
+	if response.Token == "" {
+		t.Error("no session token returned")
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, response.User.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "ada@example.com" || !user.EmailVerified || user.Password != "" {
+		t.Errorf("provisioned user = %+v", user)
+	}
+
+	// Signing in again with the same identity reuses the account.
+	authURL, cookie = startOIDCLogin(t)
+	rec = finishOIDCLogin(t, authURL, idp.authorize(t, authURL, "subject-1", "ada@example.com", true), cookie)
+	expect(t, rec, http.StatusOK)
+
+	var users int64
+	database.DB.Model(&model.User{}).Count(&users)
+	if users != 1 {
+		t.Errorf("%d users exist, want 1", users)
+	}
+}
+
+func TestOIDCLoginRejectsReplayedState(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIdP(t)
+
+	authURL, cookie := startOIDCLogin(t)
+	expect(t, finishOIDCLogin(t, authURL, idp.authorize(t, authURL, "subject-1", "ada@example.com", true), cookie), http.StatusOK)
+	expect(t, finishOIDCLogin(t, authURL, idp.authorize(t, authURL, "subject-1", "ada@example.com", true), cookie), http.StatusBadRequest)
+}
+
+func TestOIDCLoginRejectsStateFromAnotherBrowser(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIdP(t)
+
+	victimURL, _ := startOIDCLogin(t)
+	_, attackerCookie := startOIDCLogin(t)
+	rec := finishOIDCLogin(t, victimURL, idp.authorize(t, victimURL, "subject-1", "ada@example.com", true), attackerCookie)
+	expect(t, rec, http.StatusBadRequest)
+}
+
+func TestOIDCLoginRejectsPKCEMismatch(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIdP(t)
+
+	authURL, cookie := startOIDCLogin(t)
+	parsed, _ := url.Parse(authURL)
+	database.DB.Model(&model.OIDCAuthRequest{}).
+		Where("state = ?", parsed.Query().Get("state")).
+		Update("code_verifier", "a-verifier-that-does-not-match-the-challenge-sent")
+
+	rec := finishOIDCLogin(t, authURL, idp.authorize(t, authURL, "subject-1", "ada@example.com", true), cookie)
+	expect(t, rec, http.StatusUnauthorized)
+}
+
+func TestOIDCLoginLinksVerifiedEmail(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIdP(t)
+	existing := newUser(t, "Ada", "ada@example.com")
+
+	authURL, cookie := startOIDCLogin(t)
+	rec := finishOIDCLogin(t, authURL, idp.authorize(t, authURL, "subject-1", "ADA@example.com", "true"), cookie)
+	expect(t, rec, http.StatusOK)
+
+	var identity model.OIDCIdentity
+	if err := database.DB.Where("subject = ?", "subject-1").First(&identity).Error; err != nil {
+		t.Fatalf("identity was not linked: %v", err)
+	}
+	if identity.UserID != existing.ID || identity.Issuer != idp.server.URL {
+		t.Errorf("identity = %+v, want it linked to user %d", identity, existing.ID)
+	}
+}
+
+func TestOIDCLoginDoesNotLinkUnverifiedAccount(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIdP(t)
+	// Anyone could have registered this address with a password they know.
+	squatter := newUser(t, "Ada", "ada@example.com")
+	database.DB.Model(&squatter).Update("email_verified", false)
+
+	authURL, cookie := startOIDCLogin(t)
+	rec := finishOIDCLogin(t, authURL, idp.authorize(t, authURL, "subject-1", "ada@example.com", true), cookie)
+	expect(t, rec, http.StatusConflict)
+
+	var identities int64
+	database.DB.Model(&model.OIDCIdentity{}).Count(&identities)
+	if identities != 0 {
+		t.Errorf("%d identities exist, want none linked", identities)
+	}
+}
+
+func TestOIDCLoginUnverifiedEmail(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIdP(t)
+	newUser(t, "Ada", "ada@example.com")
+
+	// An unverified claim to an existing address is not linked.
+	authURL, cookie := startOIDCLogin(t)
+	rec := finishOIDCLogin(t, authURL, idp.authorize(t, authURL, "subject-1", "ada@example.com", false), cookie)
+	expect(t, rec, http.StatusConflict)
+
+	// Nor is it enough to provision a new account.
+	authURL, cookie = startOIDCLogin(t)
+	rec = finishOIDCLogin(t, authURL, idp.authorize(t, authURL, "subject-2", "bob@example.com", false), cookie)
+	expect(t, rec, http.StatusForbidden)
+
+	var identities, users int64
+	database.DB.Model(&model.OIDCIdentity{}).Count(&identities)
+	database.DB.Model(&model.User{}).Count(&users)
+	if identities != 0 || users != 1 {
+		t.Errorf("%d identities and %d users exist, want 0 and 1", identities, users)
+	}
+}
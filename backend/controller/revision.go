@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/websocket"
+	"gorm.io/gorm"
+)
+
+// GetTaskRevisions lists the snapshots a task can be reverted to, newest
+// first. Pages are requested with limit and the previous page's next_cursor.
+func GetTaskRevisions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID := r.PathValue("id")
+
+	var task model.Task
+	if err := database.DB.Unscoped().Scopes(visibleTasks(userID)).Where("tasks.id = ?", taskID).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	limit, _ := parsePagination(r, 50, 200)
+
+	query := database.DB.Where("task_id = ?", task.ID)
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		before, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("version < ?", before)
+	}
+
+	var revisions []model.TaskRevision
+	if err := query.Order("version DESC").Limit(limit + 1).Find(&revisions).Error; err != nil {
+		http.Error(w, "Could not retrieve task revisions", http.StatusInternalServerError)
+		return
+	}
+
+	var nextCursor string
+	if len(revisions) > limit {
+		revisions = revisions[:limit]
+		nextCursor = strconv.FormatUint(uint64(revisions[limit-1].Version), 10)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"revisions":   revisions,
+		"next_cursor": nextCursor,
+	})
+}
+
+// RevertTask restores a task's fields to those of an earlier revision. The
+// revert is saved as a new revision, so it can itself be undone.
+func RevertTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID := r.PathValue("id")
+
+	var task model.Task
+	if err := database.DB.Scopes(editableTasks(userID)).Where("tasks.id = ?", taskID).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if !checkIfMatch(w, r, task) {
+		return
+	}
+
+	var input model.RevertTaskInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Revision == 0 {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	var revision model.TaskRevision
+	if err := database.DB.Where("task_id = ? AND version = ?", task.ID, input.Revision).First(&revision).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	before := task
+	task.Title = revision.Title
+	task.Description = revision.Description
+	task.Status = revision.Status
+	task.Priority = revision.Priority
+	task.DueDate = revision.DueDate
+	task.AssignedTo = revision.AssignedTo
+
+	// The earlier assignee may have left the user's workspaces since.
+	if task.AssignedTo != before.AssignedTo && !checkAssignee(w, userID, task.AssignedTo) {
+		return
+	}
+
+	if err := updateTaskWithHistory(r, model.TaskActionReverted, before, &task); err != nil {
+		if err == errTaskVersionConflict {
+			writeVersionConflict(w, r)
+			return
+		}
+		if writeTaskBlocked(w, err) {
+			return
+		}
+		http.Error(w, "Could not revert task", http.StatusInternalServerError)
+		return
+	}
+
+	websocket.BroadcastTaskUpdate(task, "updated", taskWatchers(&task))
+	if task.AssignedTo != before.AssignedTo {
+		autoWatch(task.ID, task.AssignedTo)
+	}
+	recordMentions(&task, userID, before.Description, task.Description, nil)
+	notifyTaskChanges(&task, &before, userID)
+	if task.Status != before.Status {
+		syncDependents(r, task.ID, userID)
+	}
+
+	w.Header().Set("ETag", taskETag(task))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"task": task})
+}
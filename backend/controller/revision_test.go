@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestRevertTask(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	other := newUser(t, "Bob", "bob@example.com")
+	task := newTask(t, owner, map[string]interface{}{"title": "Draft", "status": "pending"})
+
+	patch := map[string]interface{}{"title": "Final", "status": "in_progress"}
+	expect(t, serve(PatchTask, taskRequest(t, http.MethodPatch, owner, task.ID, patch)), http.StatusOK)
+
+	rec := serve(GetTaskRevisions, taskRequest(t, http.MethodGet, owner, task.ID, nil))
+	expect(t, rec, http.StatusOK)
+	var listed struct {
+		Revisions []model.TaskRevision `json:"revisions"`
+	}
+	decode(t, rec, &listed)
+	if len(listed.Revisions) != 2 || listed.Revisions[0].Title != "Final" || listed.Revisions[1].Title != "Draft" {
+		t.Fatalf("revisions = %+v, want Final then Draft", listed.Revisions)
+	}
+	first := listed.Revisions[1].Version
+
+	revert := func(user model.User, revision uint, ifMatch string) *http.Request {
+		r := taskRequest(t, http.MethodPost, user, task.ID, map[string]uint{"revision": revision})
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		return r
+	}
+
+	expect(t, serve(RevertTask, revert(other, first, "")), http.StatusNotFound)
+	expect(t, serve(RevertTask, revert(owner, 99, "")), http.StatusNotFound)
+	expect(t, serve(RevertTask, revert(owner, first, taskETag(task))), http.StatusPreconditionFailed)
+
+	rec = serve(RevertTask, revert(owner, first, ""))
+	expect(t, rec, http.StatusOK)
+	var reverted struct {
+		Task model.Task `json:"task"`
+	}
+	decode(t, rec, &reverted)
+	if reverted.Task.Title != "Draft" || reverted.Task.Status != "pending" || reverted.Task.Version != task.Version+2 {
+		t.Errorf("reverted task = %+v", reverted.Task)
+	}
+
+	// The revert is itself a revision, so it can be undone.
+	var revisions int64
+	database.DB.Model(&model.TaskRevision{}).Where("task_id = ?", task.ID).Count(&revisions)
+	if revisions != 3 {
+		t.Errorf("%d revisions, want 3", revisions)
+	}
+	var history model.TaskHistory
+	database.DB.Where("task_id = ?", task.ID).Order("id DESC").First(&history)
+	if history.Action != model.TaskActionReverted || history.ActorID != owner.ID {
+		t.Errorf("last history entry = %+v", history)
+	}
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+// Highlight markers are private-use characters so that highlighted snippets
+// can be HTML-escaped before the markers are turned into <mark> tags.
+const (
+	highlightStart  = "\uE000"
+	highlightStop   = "\uE001"
+	headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop
+	snippetOptions  = headlineOptions + ", MaxFragments=2, MaxWords=20, MinWords=5"
+)
+
+// searchQuery turns free text into a tsquery that matches every word as a
+// prefix. Anything other than letters and digits is treated as a separator,
+// so user input cannot inject tsquery operators.
+func searchQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, word+":*")
+	}
+	return strings.Join(terms, " & ")
+}
+
+func highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
+
+type taskSearchRow struct {
+	model.Task
+	Rank               float64
+	TitleSnippet       string
+	DescriptionSnippet string
+}
+
+// SearchTasks ranks the caller's tasks against q, matching words in the title
+// and description by prefix. Snippets are HTML with matches in <mark> tags.
+func SearchTasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := searchQuery(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Search query is required", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset := parsePagination(r, 20, 100)
+
+	var rows []taskSearchRow
+	err := database.DB.Model(&model.Task{}).
+		Select("tasks.*, ts_rank(tasks.search_vector, search_query) AS rank, "+
+			"ts_headline('english', tasks.title, search_query, ?) AS title_snippet, "+
+			"ts_headline('english', tasks.description, search_query, ?) AS description_snippet",
+			headlineOptions, snippetOptions).
+		Joins("CROSS JOIN to_tsquery('english', ?) AS search_query", query).
+		Scopes(visibleTasks(userID)).
+		Where("tasks.search_vector @@ search_query").
+		Order("rank DESC, tasks.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&rows).Error
+	if err != nil {
+		http.Error(w, "Could not search tasks", http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, map[string]interface{}{
+			"task": row.Task,
+			"rank": row.Rank,
+			"highlights": map[string]string{
+				"title":       highlight(row.TitleSnippet),
+				"description": highlight(row.DescriptionSnippet),
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+}
@@ -0,0 +1,40 @@
+package controller
+
+import "testing"
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"invoice", "invoice:*"},
+		{"  quarterly   report ", "quarterly:* & report:*"},
+		{"it's Q4", "it:* & s:* & Q4:*"},
+		{"a & !b | c:* <-> (d)", "a:* & b:* & c:* & d:*"},
+		{"café naïve", "café:* & naïve:*"},
+		{"!!!", ""},
+	}
+	for _, tt := range tests {
+		if got := searchQuery(tt.text); got != tt.want {
+			t.Errorf("searchQuery(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"plain text", "plain text"},
+		{"the " + highlightStart + "invoice" + highlightStop + " is due", "the <mark>invoice</mark> is due"},
+		{"<script>" + highlightStart + "x" + highlightStop + "</script>", "&lt;script&gt;<mark>x</mark>&lt;/script&gt;"},
+		{`"a" & 'b'`, "&#34;a&#34; &amp; &#39;b&#39;"},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.snippet); got != tt.want {
+			t.Errorf("highlight(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
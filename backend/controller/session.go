@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/websocket"
+)
+
+// startSession records a new login session for the user and returns a JWT bound to it.
+func startSession(r *http.Request, userID uint) (string, error) {
+	sessionID, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	session := model.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		UserAgent:  r.UserAgent(),
+		IP:         middleware.ClientIP(r),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(middleware.SessionTTL),
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return "", err
+	}
+
+	return middleware.GenerateToken(userID, session.ID, session.ExpiresAt)
+}
+
+// revokeOtherSessions revokes every active session of the user except keepID
+// and closes the other connections still open on their behalf.
+func revokeOtherSessions(userID uint, keepID string) error {
+	err := database.DB.Model(&model.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	websocket.DisconnectUser(userID, keepID)
+	return nil
+}
+
+func GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var sessions []model.Session
+	err := database.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		http.Error(w, "Could not retrieve sessions", http.StatusInternalServerError)
+		return
+	}
+
+	currentID, _ := r.Context().Value(middleware.SessionIDKey).(string)
+	response := make([]map[string]interface{}, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, map[string]interface{}{
+			"id":           session.ID,
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"created_at":   session.CreatedAt,
+			"last_seen_at": session.LastSeenAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.ID == currentID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"sessions": response})
+}
+
+func RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := r.PathValue("id")
+
+	result := database.DB.Model(&model.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		http.Error(w, "Could not revoke session", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	websocket.DisconnectSession(userID, sessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Session revoked successfully"})
+}
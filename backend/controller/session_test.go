@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+// authenticates reports whether AuthMiddleware accepts token.
+func authenticates(t *testing.T, token string) bool {
+	t.Helper()
+	r := request(t, http.MethodGet, "/api/users/me", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	rec := serve(middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), r)
+	return rec.Code == http.StatusNoContent
+}
+
+// login starts a session for user and returns its ID and token.
+func login(t *testing.T, user model.User) (string, string) {
+	t.Helper()
+	token, err := startSession(request(t, http.MethodPost, "/api/auth/login", nil), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		t.Fatal(err)
+	}
+	sessionID, _ := claims["sid"].(string)
+	return sessionID, token
+}
+
+func TestGetSessions(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+	user := newUser(t, "Ada", "ada@example.com")
+
+	current, _ := login(t, user)
+	other, _ := login(t, user)
+	revoked, _ := login(t, user)
+	database.DB.Model(&model.Session{}).Where("id = ?", revoked).Update("revoked_at", time.Now())
+	login(t, newUser(t, "Bob", "bob@example.com"))
+
+	rec := serve(GetSessions, as(request(t, http.MethodGet, "/api/sessions", nil), user.ID, current))
+	expect(t, rec, http.StatusOK)
+
+	var response struct {
+		Sessions []struct {
+			ID      string `json:"id"`
+			Current bool   `json:"current"`
+		} `json:"sessions"`
+	}
+	decode(t, rec, &response)
+
+	listed := map[string]bool{}
+	for _, session := range response.Sessions {
+		listed[session.ID] = session.Current
+	}
+	if len(listed) != 2 || !listed[current] || listed[other] {
+		t.Errorf("sessions = %+v, want %s (current) and %s", response.Sessions, current, other)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+	user := newUser(t, "Ada", "ada@example.com")
+	intruder := newUser(t, "Bob", "bob@example.com")
+
+	current, currentToken := login(t, user)
+	other, otherToken := login(t, user)
+	if !authenticates(t, otherToken) {
+		t.Fatal("a fresh session is not accepted")
+	}
+
+	revoke := func(userID uint, sessionID string) int {
+		r := as(request(t, http.MethodDelete, "/api/sessions/"+sessionID, nil), userID, current)
+		r.SetPathValue("id", sessionID)
+		return serve(RevokeSession, r).Code
+	}
+
+	if status := revoke(intruder.ID, other); status != http.StatusNotFound {
+		t.Errorf("revoking another user's session: status = %d, want %d", status, http.StatusNotFound)
+	}
+	if !authenticates(t, otherToken) {
+		t.Error("another user revoked the session")
+	}
+
+	if status := revoke(user.ID, other); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if authenticates(t, otherToken) {
+		t.Error("a revoked session is still accepted")
+	}
+	if !authenticates(t, currentToken) {
+		t.Error("revoking one session signed out another")
+	}
+	if status := revoke(user.ID, other); status != http.StatusNotFound {
+		t.Errorf("revoking twice: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestAuthMiddlewareRejectsExpiredSession(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+	user := newUser(t, "Ada", "ada@example.com")
+
+	sessionID, token := login(t, user)
+	database.DB.Model(&model.Session{}).Where("id = ?", sessionID).Update("expires_at", time.Now().Add(-time.Minute))
+	if authenticates(t, token) {
+		t.Error("an expired session is still accepted")
+	}
+}
@@ -2,14 +2,21 @@ package controller
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/config"
 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/websocket"
 	"gorm.io/gorm"
 )
 
+// GetAllTasks lists the caller's tasks a page at a time. See applyTaskFilters
+// for the supported filters and taskSortColumns for the sortable fields.
 func GetAllTasks(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
 	if !ok {
@@ -17,14 +24,19 @@ func GetAllTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var tasks []model.Task
-	if err := database.DB.Where("assigned_to = ?", userID).Find(&tasks).Error; err != nil {
-		http.Error(w, "Could not retrieve tasks", http.StatusInternalServerError)
+	sort, err := parseTaskSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"tasks": tasks})
+	query, err := applyTaskFilters(database.DB.Model(&model.Task{}), r, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeTaskPage(w, r, query, sort)
 }
 
 func GetTaskByID(w http.ResponseWriter, r *http.Request) {
@@ -37,7 +49,7 @@ func GetTaskByID(w http.ResponseWriter, r *http.Request) {
 	taskID := r.PathValue("id")
 
 	var task model.Task
-	if err := database.DB.Where("id = ? AND (assigned_to = ? OR created_by = ?)", taskID, userID, userID).First(&task).Error; err != nil {
+	if err := database.DB.Scopes(visibleTasks(userID)).Preload("Labels").Where("tasks.id = ?", taskID).First(&task).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Task not found", http.StatusNotFound)
 			return
@@ -46,6 +58,12 @@ func GetTaskByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", taskETag(task))
+	if header := r.Header.Get("If-None-Match"); header != "" && etagMatches(header, taskETag(task), true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"task": task})
 }
@@ -76,19 +94,35 @@ func CreateTask(w http.ResponseWriter, r *http.Request) {
 		DueDate:     input.DueDate,
 		AssignedTo:  input.AssignedTo,
 		CreatedBy:   userID,
+		Version:     1,
 	}
 
 	if task.AssignedTo == 0 {
 		task.AssignedTo = userID
 	}
+	if !checkAssignee(w, userID, task.AssignedTo) {
+		return
+	}
 
-	if err := database.DB.Create(&task).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		if err := watchTask(tx, task.ID, task.CreatedBy, task.AssignedTo); err != nil {
+			return err
+		}
+		return recordTaskHistory(tx, r, task, model.TaskActionCreated, taskChanges(nil, task))
+	})
+	if err != nil {
 		http.Error(w, "Could not create task", http.StatusInternalServerError)
 		return
 	}
 
-	// websocket.BroadcastTaskUpdate(task, "created")
+	websocket.BroadcastTaskUpdate(task, "created", taskWatchers(&task))
+	recordMentions(&task, userID, "", task.Description, nil)
+	notifyTaskChanges(&task, nil, userID)
 
+	w.Header().Set("ETag", taskETag(task))
 	w.Header().Set("Content-Type", "application/json")
 
 	w.WriteHeader(http.StatusCreated)
@@ -105,7 +139,7 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
 	taskID := r.PathValue("id")
 
 	var task model.Task
-	if err := database.DB.Where("id = ? AND (assigned_to = ? OR created_by = ?)", taskID, userID, userID).First(&task).Error; err != nil {
+	if err := database.DB.Scopes(editableTasks(userID)).Where("tasks.id = ?", taskID).First(&task).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Task not found", http.StatusNotFound)
 			return
@@ -114,41 +148,234 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkIfMatch(w, r, task) {
+		return
+	}
+
 	var input model.TaskInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, "Invalid input data", http.StatusBadRequest)
 		return
 	}
 
-	// Update fields
-	if input.Title != "" {
-		task.Title = input.Title
+	if input.Title == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return
 	}
+
+	before := task
+
+	// PUT replaces every editable field; omitted fields are cleared.
+	// Use PATCH to change individual fields.
+	task.Title = input.Title
 	task.Description = input.Description
-	if input.Status != "" {
-		task.Status = input.Status
+	task.Status = input.Status
+	task.Priority = input.Priority
+	task.DueDate = input.DueDate
+	task.AssignedTo = input.AssignedTo
+
+	if task.AssignedTo != before.AssignedTo && !checkAssignee(w, userID, task.AssignedTo) {
+		return
 	}
-	if input.Priority != "" {
-		task.Priority = input.Priority
+
+	if err := updateTaskWithHistory(r, model.TaskActionUpdated, before, &task); err != nil {
+		if err == errTaskVersionConflict {
+			writeVersionConflict(w, r)
+			return
+		}
+		if writeTaskBlocked(w, err) {
+			return
+		}
+		http.Error(w, "Could not update task", http.StatusInternalServerError)
+		return
 	}
-	if !input.DueDate.IsZero() {
-		task.DueDate = input.DueDate
+
+	websocket.BroadcastTaskUpdate(task, "updated", taskWatchers(&task))
+	if task.AssignedTo != before.AssignedTo {
+		autoWatch(task.ID, task.AssignedTo)
 	}
-	if input.AssignedTo != 0 {
-		task.AssignedTo = input.AssignedTo
+	recordMentions(&task, userID, before.Description, task.Description, nil)
+	notifyTaskChanges(&task, &before, userID)
+	if task.Status != before.Status {
+		syncDependents(r, task.ID, userID)
 	}
 
-	if err := database.DB.Save(&task).Error; err != nil {
+	w.Header().Set("ETag", taskETag(task))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"task": task})
+}
+
+// PatchTask applies a JSON Merge Patch (RFC 7396) to a task. Fields that are
+// absent are left untouched and fields set to null are cleared.
+func PatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID := r.PathValue("id")
+
+	var task model.Task
+	if err := database.DB.Scopes(editableTasks(userID)).Where("tasks.id = ?", taskID).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if !checkIfMatch(w, r, task) {
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	before := task
+	if err := applyTaskPatch(&task, patch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if task.AssignedTo != before.AssignedTo && !checkAssignee(w, userID, task.AssignedTo) {
+		return
+	}
+
+	if err := updateTaskWithHistory(r, model.TaskActionUpdated, before, &task); err != nil {
+		if err == errTaskVersionConflict {
+			writeVersionConflict(w, r)
+			return
+		}
+		if writeTaskBlocked(w, err) {
+			return
+		}
 		http.Error(w, "Could not update task", http.StatusInternalServerError)
 		return
 	}
 
-	// websocket.BroadcastTaskUpdate(task, "updated")
+	websocket.BroadcastTaskUpdate(task, "updated", taskWatchers(&task))
+	if task.AssignedTo != before.AssignedTo {
+		autoWatch(task.ID, task.AssignedTo)
+	}
+	recordMentions(&task, userID, before.Description, task.Description, nil)
+	notifyTaskChanges(&task, &before, userID)
+	if task.Status != before.Status {
+		syncDependents(r, task.ID, userID)
+	}
 
+	w.Header().Set("ETag", taskETag(task))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"task": task})
 }
 
+// sharesWorkspace reports whether the other user exists and belongs to one of
+// the user's workspaces.
+func sharesWorkspace(userID, otherID uint) (bool, error) {
+	var count int64
+	err := database.DB.Table("workspace_members AS mine").
+		Joins("JOIN workspace_members AS theirs ON theirs.workspace_id = mine.workspace_id").
+		Joins("JOIN users ON users.id = theirs.user_id").
+		Where("mine.user_id = ? AND theirs.user_id = ?", userID, otherID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// checkAssignee writes an error response unless a task may be assigned to
+// assignee by the user: tasks can be left unassigned, or assigned to the user
+// or to someone sharing a workspace with them. Unknown users and strangers get
+// the same answer, so user IDs cannot be probed.
+func checkAssignee(w http.ResponseWriter, userID, assignee uint) bool {
+	if assignee == 0 || assignee == userID {
+		return true
+	}
+
+	shared, err := sharesWorkspace(userID, assignee)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return false
+	}
+	if !shared {
+		http.Error(w, "Tasks can only be assigned to members of your workspaces", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// updateTaskWithHistory saves task and records how it differs from before.
+// While any of its blockers is open a task cannot be completed, and other
+// statuses only take effect once the blockers are done.
+func updateTaskWithHistory(r *http.Request, action string, before model.Task, task *model.Task) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if task.Status != before.Status {
+			// A task marked "blocked" by hand stays so until changed by hand.
+			task.StatusBeforeBlocked = ""
+			if task.Status != "blocked" {
+				if err := lockDependencies(tx, false); err != nil {
+					return err
+				}
+				blockers, err := openBlockers(tx, task.ID)
+				if err != nil {
+					return err
+				}
+				if len(blockers) > 0 && task.Status == "completed" {
+					return &taskBlockedError{blockers: blockers}
+				}
+				if len(blockers) > 0 {
+					task.StatusBeforeBlocked = task.Status
+					task.Status = "blocked"
+				}
+			}
+		}
+		if err := saveTask(tx, task); err != nil {
+			return err
+		}
+		return recordTaskHistory(tx, r, *task, action, taskChanges(&before, *task))
+	})
+}
+
+func applyTaskPatch(task *model.Task, patch map[string]json.RawMessage) error {
+	for field, raw := range patch {
+		isNull := string(raw) == "null"
+
+		var err error
+		switch field {
+		case "title":
+			if isNull {
+				return fmt.Errorf("title cannot be cleared")
+			}
+			err = json.Unmarshal(raw, &task.Title)
+			if err == nil && task.Title == "" {
+				return fmt.Errorf("title cannot be empty")
+			}
+		case "description":
+			task.Description = ""
+			err = json.Unmarshal(raw, &task.Description)
+		case "status":
+			task.Status = ""
+			err = json.Unmarshal(raw, &task.Status)
+		case "priority":
+			task.Priority = ""
+			err = json.Unmarshal(raw, &task.Priority)
+		case "due_date":
+			task.DueDate = nil
+			err = json.Unmarshal(raw, &task.DueDate)
+		case "assigned_to":
+			task.AssignedTo = 0
+			err = json.Unmarshal(raw, &task.AssignedTo)
+		default:
+			return fmt.Errorf("unknown or read-only field: %s", field)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for %s", field)
+		}
+	}
+	return nil
+}
+
 func DeleteTask(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
 	if !ok {
@@ -159,7 +386,7 @@ func DeleteTask(w http.ResponseWriter, r *http.Request) {
 	taskID := r.PathValue("id")
 
 	var task model.Task
-	if err := database.DB.Where("id = ? AND (assigned_to = ? OR created_by = ?)", taskID, userID, userID).First(&task).Error; err != nil {
+	if err := database.DB.Scopes(editableTasks(userID)).Where("tasks.id = ?", taskID).First(&task).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Task not found", http.StatusNotFound)
 			return
@@ -168,13 +395,187 @@ func DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := database.DB.Delete(&task).Error; err != nil {
+	if !checkIfMatch(w, r, task) {
+		return
+	}
+
+	// Deleted tasks go to the trash and are purged after the retention period.
+	deletedAt := time.Now()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&task).Where("version = ?", task.Version).Updates(map[string]interface{}{
+			"deleted_at": deletedAt,
+			"version":    task.Version + 1,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errTaskVersionConflict
+		}
+		task.Version++
+		return recordTaskHistory(tx, r, task, model.TaskActionDeleted, map[string]model.FieldChange{
+			"deleted_at": {Old: nil, New: deletedAt},
+		})
+	})
+	if err != nil {
+		if err == errTaskVersionConflict {
+			writeVersionConflict(w, r)
+			return
+		}
 		http.Error(w, "Could not delete task", http.StatusInternalServerError)
 		return
 	}
 
-	// websocket.BroadcastTaskUpdate(task, "deleted")
+	websocket.BroadcastTaskUpdate(task, "deleted", taskWatchers(&task))
+	syncDependents(r, task.ID, userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Task moved to trash"})
+}
+
+func GetTrashedTasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var tasks []model.Task
+	err := database.DB.Unscoped().
+		Scopes(editableTasks(userID)).Where("tasks.deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Find(&tasks).Error
+	if err != nil {
+		http.Error(w, "Could not retrieve tasks", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Task deleted successfully"})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tasks":          tasks,
+		"retention_days": int(taskTrashRetention().Hours() / 24),
+	})
+}
+
+func RestoreTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID := r.PathValue("id")
+
+	var task model.Task
+	err := database.DB.Unscoped().
+		Scopes(editableTasks(userID)).Where("tasks.id = ? AND tasks.deleted_at IS NOT NULL", taskID).
+		First(&task).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found in trash", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if !checkIfMatch(w, r, task) {
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&task).Where("version = ?", task.Version).Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    task.Version + 1,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errTaskVersionConflict
+		}
+		deletedAt := task.DeletedAt.Time
+		task.DeletedAt = gorm.DeletedAt{}
+		task.Version++
+		return recordTaskHistory(tx, r, task, model.TaskActionRestored, map[string]model.FieldChange{
+			"deleted_at": {Old: deletedAt, New: nil},
+		})
+	})
+	if err != nil {
+		if err == errTaskVersionConflict {
+			writeVersionConflict(w, r)
+			return
+		}
+		http.Error(w, "Could not restore task", http.StatusInternalServerError)
+		return
+	}
+
+	websocket.BroadcastTaskUpdate(task, "restored", taskWatchers(&task))
+	syncDependents(r, task.ID, userID)
+
+	w.Header().Set("ETag", taskETag(task))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"task": task})
+}
+
+func taskTrashRetention() time.Duration {
+	return config.Duration("TASK_TRASH_RETENTION", 30*24*time.Hour)
+}
+
+// PurgeTrashedTasks permanently deletes tasks that have been in the trash for
+// longer than the retention period.
+func PurgeTrashedTasks() {
+	cutoff := time.Now().Add(-taskTrashRetention())
+
+	var purged int64
+	var attachments []model.Attachment
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Unscoped().Model(&model.Task{}).Select("id").Where("deleted_at < ?", cutoff)
+		if err := tx.Select("storage_key", "thumbnail_key").Where("task_id IN (?)", expired).Find(&attachments).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id IN (?)", expired).Delete(&model.Attachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id IN (?)", expired).Delete(&model.TaskRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM task_labels WHERE task_id IN (?)", expired).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id IN (?)", expired).Delete(&model.TaskAccess{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id IN (?)", expired).Delete(&model.Notification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id IN (?)", expired).Delete(&model.DueSoonReminder{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id IN (?)", expired).Delete(&model.TaskWatcher{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("blocker_id IN (?) OR blocked_id IN (?)", expired, expired).Delete(&model.TaskDependency{}).Error; err != nil {
+			return err
+		}
+		comments := tx.Unscoped().Model(&model.Comment{}).Select("id").Where("task_id IN (?)", expired)
+		if err := tx.Where("comment_id IN (?)", comments).Delete(&model.CommentEdit{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("task_id IN (?)", expired).Delete(&model.Comment{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("deleted_at < ?", cutoff).Delete(&model.Task{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		log.Printf("[Purge] Could not purge trashed tasks: %v", err)
+		return
+	}
+	// Files are only removed once nothing refers to them any more.
+	deleteStoredFiles(attachmentFiles(attachments...))
+	if purged > 0 {
+		log.Printf("[Purge] Purged %d trashed task(s)", purged)
+	}
 }
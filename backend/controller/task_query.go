@@ -0,0 +1,314 @@
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+)
+
+const (
+	sortKindNumber = iota
+	sortKindString
+	sortKindTime
+)
+
+// taskSortColumns lists the fields tasks can be sorted by and how their
+// values are encoded in a cursor.
+var taskSortColumns = map[string]int{
+	"id":          sortKindNumber,
+	"title":       sortKindString,
+	"description": sortKindString,
+	"status":      sortKindString,
+	"priority":    sortKindString,
+	"due_date":    sortKindTime,
+	"assigned_to": sortKindNumber,
+	"created_by":  sortKindNumber,
+	"version":     sortKindNumber,
+	"created_at":  sortKindTime,
+	"updated_at":  sortKindTime,
+}
+
+// taskSort orders a task listing by one field, with the ID as tie-breaker.
+type taskSort struct {
+	Field string
+	Desc  bool
+}
+
+// parseTaskSort reads a sort parameter such as "due_date" or "-created_at".
+func parseTaskSort(raw string) (taskSort, error) {
+	if raw == "" {
+		return taskSort{Field: "id"}, nil
+	}
+	sort := taskSort{Field: strings.TrimPrefix(raw, "-"), Desc: strings.HasPrefix(raw, "-")}
+	if _, ok := taskSortColumns[sort.Field]; !ok {
+		return taskSort{}, fmt.Errorf("cannot sort by %s", sort.Field)
+	}
+	return sort, nil
+}
+
+func (s taskSort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// apply orders query by the sort and, given a cursor, skips the rows up to
+// and including the one it points at. NULLs sort last in both directions.
+func (s taskSort) apply(query *gorm.DB, cursor *taskCursor) *gorm.DB {
+	column := "tasks." + s.Field
+	direction, op := "ASC", ">"
+	if s.Desc {
+		direction, op = "DESC", "<"
+	}
+
+	if cursor != nil {
+		switch {
+		case s.Field == "id":
+			query = query.Where("tasks.id "+op+" ?", cursor.ID)
+		case cursor.value == nil:
+			query = query.Where(column+" IS NULL AND tasks.id "+op+" ?", cursor.ID)
+		default:
+			query = query.Where(
+				"("+column+" "+op+" ? OR ("+column+" = ? AND tasks.id "+op+" ?) OR "+column+" IS NULL)",
+				cursor.value, cursor.value, cursor.ID)
+		}
+	}
+
+	if s.Field == "id" {
+		return query.Order("tasks.id " + direction)
+	}
+	return query.Order(fmt.Sprintf("%s %s NULLS LAST, tasks.id %s", column, direction, direction))
+}
+
+// taskCursor marks the last task of a page. It records the sort it was
+// issued for so it cannot be replayed against a different ordering.
+type taskCursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+
+	value interface{}
+}
+
+func encodeTaskCursor(sort taskSort, task model.Task) string {
+	var value interface{}
+	switch sort.Field {
+	case "title":
+		value = task.Title
+	case "description":
+		value = task.Description
+	case "status":
+		value = task.Status
+	case "priority":
+		value = task.Priority
+	case "due_date":
+		value = task.DueDate
+	case "assigned_to":
+		value = task.AssignedTo
+	case "created_by":
+		value = task.CreatedBy
+	case "version":
+		value = task.Version
+	case "created_at":
+		value = task.CreatedAt
+	case "updated_at":
+		value = task.UpdatedAt
+	}
+
+	raw, _ := json.Marshal(value)
+	data, _ := json.Marshal(taskCursor{Sort: sort.String(), Value: raw, ID: task.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTaskCursor(raw string, sort taskSort) (*taskCursor, error) {
+	invalid := fmt.Errorf("invalid cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor taskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort.String() {
+		return nil, invalid
+	}
+
+	if len(cursor.Value) == 0 || string(cursor.Value) == "null" {
+		return &cursor, nil
+	}
+	switch taskSortColumns[sort.Field] {
+	case sortKindNumber:
+		var v uint64
+		err = json.Unmarshal(cursor.Value, &v)
+		cursor.value = v
+	case sortKindString:
+		var v string
+		err = json.Unmarshal(cursor.Value, &v)
+		cursor.value = v
+	case sortKindTime:
+		var v time.Time
+		err = json.Unmarshal(cursor.Value, &v)
+		cursor.value = v
+	}
+	if err != nil {
+		return nil, invalid
+	}
+	return &cursor, nil
+}
+
+// writeTaskPage responds with the page of query's tasks after the request's
+// cursor, in sort order, and the cursor for the page that follows.
+func writeTaskPage(w http.ResponseWriter, r *http.Request, query *gorm.DB, sort taskSort) {
+	var cursor *taskCursor
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		var err error
+		if cursor, err = decodeTaskCursor(raw, sort); err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit, _ := parsePagination(r, 50, 200)
+
+	var tasks []model.Task
+	if err := sort.apply(query, cursor).Preload("Labels").Limit(limit + 1).Find(&tasks).Error; err != nil {
+		http.Error(w, "Could not retrieve tasks", http.StatusInternalServerError)
+		return
+	}
+
+	var nextCursor string
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		nextCursor = encodeTaskCursor(sort, tasks[limit-1])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tasks":       tasks,
+		"next_cursor": nextCursor,
+	})
+}
+
+// editableTasks limits a task query to the tasks userID may change: those
+// assigned to or created by them.
+func editableTasks(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(tasks.assigned_to = ? OR tasks.created_by = ?)", userID, userID)
+	}
+}
+
+// visibleTasks limits a task query to the tasks userID may see: the ones
+// they can edit, those they were given read access to and those they watch.
+func visibleTasks(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(tasks.assigned_to = ? OR tasks.created_by = ? "+
+			"OR tasks.id IN (SELECT task_id FROM task_accesses WHERE user_id = ?) "+
+			"OR tasks.id IN (SELECT task_id FROM task_watchers WHERE user_id = ?))",
+			userID, userID, userID, userID)
+	}
+}
+
+// applyTaskFilters narrows query to the caller's tasks and the filters given
+// in the query string.
+func applyTaskFilters(query *gorm.DB, r *http.Request, userID uint) (*gorm.DB, error) {
+	params := r.URL.Query()
+
+	switch params.Get("scope") {
+	case "", "assigned":
+		query = query.Where("tasks.assigned_to = ?", userID)
+	case "created":
+		query = query.Where("tasks.created_by = ?", userID)
+	case "all":
+		query = query.Scopes(visibleTasks(userID))
+	default:
+		return nil, fmt.Errorf("scope must be assigned, created or all")
+	}
+
+	if statuses := splitParam(params.Get("status")); len(statuses) > 0 {
+		query = query.Where("tasks.status IN ?", statuses)
+	}
+	if priorities := splitParam(params.Get("priority")); len(priorities) > 0 {
+		query = query.Where("tasks.priority IN ?", priorities)
+	}
+
+	for param, column := range map[string]string{"assigned_to": "tasks.assigned_to", "created_by": "tasks.created_by"} {
+		values := splitParam(params.Get(param))
+		if len(values) == 0 {
+			continue
+		}
+		ids := make([]uint64, 0, len(values))
+		for _, value := range values {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s", param)
+			}
+			ids = append(ids, id)
+		}
+		query = query.Where(column+" IN ?", ids)
+	}
+
+	if values := splitParam(params.Get("label")); len(values) > 0 {
+		labelIDs := make([]uint64, 0, len(values))
+		for _, value := range values {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for label")
+			}
+			labelIDs = append(labelIDs, id)
+		}
+		query = query.Where("tasks.id IN (SELECT task_id FROM task_labels WHERE label_id IN ?)", labelIDs)
+	}
+
+	if raw := params.Get("due_after"); raw != "" {
+		after, err := parseDateParam(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for due_after")
+		}
+		query = query.Where("tasks.due_date >= ?", after)
+	}
+	if raw := params.Get("due_before"); raw != "" {
+		before, err := parseDateParam(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for due_before")
+		}
+		query = query.Where("tasks.due_date < ?", before)
+	}
+
+	switch params.Get("overdue") {
+	case "":
+	case "true":
+		query = query.Where("tasks.due_date < ? AND tasks.status <> ?", time.Now(), "completed")
+	case "false":
+		query = query.Where("(tasks.due_date IS NULL OR tasks.due_date >= ? OR tasks.status = ?)", time.Now(), "completed")
+	default:
+		return nil, fmt.Errorf("overdue must be true or false")
+	}
+
+	return query, nil
+}
+
+// splitParam splits a comma separated query parameter, dropping empty items.
+func splitParam(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// parseDateParam accepts an RFC 3339 timestamp or a plain date (midnight UTC).
+func parseDateParam(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
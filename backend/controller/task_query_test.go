@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestParseTaskSort(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want taskSort
+		err  bool
+	}{
+		{"", taskSort{Field: "id"}, false},
+		{"due_date", taskSort{Field: "due_date"}, false},
+		{"-created_at", taskSort{Field: "created_at", Desc: true}, false},
+		{"password", taskSort{}, true},
+		{"title; DROP TABLE tasks", taskSort{}, true},
+		{"--id", taskSort{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTaskSort(tt.raw)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseTaskSort(%q) = %+v, %v, want %+v, error %t", tt.raw, got, err, tt.want, tt.err)
+		}
+		if err == nil && tt.raw != "" && got.String() != tt.raw {
+			t.Errorf("parseTaskSort(%q).String() = %q", tt.raw, got.String())
+		}
+	}
+}
+
+func TestTaskCursorRoundTrip(t *testing.T) {
+	due := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+	task := model.Task{
+		ID:         42,
+		Title:      "Write report",
+		Status:     "pending",
+		DueDate:    &due,
+		AssignedTo: 7,
+		Version:    3,
+		CreatedAt:  due.Add(-time.Hour),
+	}
+
+	tests := []struct {
+		sort  string
+		value interface{}
+	}{
+		{"id", nil},
+		{"title", "Write report"},
+		{"-status", "pending"},
+		{"due_date", due},
+		{"assigned_to", uint64(7)},
+		{"-version", uint64(3)},
+		{"created_at", due.Add(-time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			sort, err := parseTaskSort(tt.sort)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cursor, err := decodeTaskCursor(encodeTaskCursor(sort, task), sort)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if cursor.ID != task.ID {
+				t.Errorf("ID = %d, want %d", cursor.ID, task.ID)
+			}
+			if value, ok := cursor.value.(time.Time); ok {
+				if !value.Equal(tt.value.(time.Time)) {
+					t.Errorf("value = %v, want %v", value, tt.value)
+				}
+			} else if !reflect.DeepEqual(cursor.value, tt.value) {
+				t.Errorf("value = %#v, want %#v", cursor.value, tt.value)
+			}
+		})
+	}
+
+	t.Run("null due date", func(t *testing.T) {
+		sort := taskSort{Field: "due_date"}
+		cursor, err := decodeTaskCursor(encodeTaskCursor(sort, model.Task{ID: 5}), sort)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if cursor.value != nil || cursor.ID != 5 {
+			t.Errorf("cursor = %+v, want a nil value for task 5", cursor)
+		}
+	})
+}
+
+func TestDecodeTaskCursorRejects(t *testing.T) {
+	title := taskSort{Field: "title"}
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := map[string]string{
+		"not base64":          "%%%",
+		"not JSON":            encode("not json"),
+		"other sort":          encodeTaskCursor(taskSort{Field: "title", Desc: true}, model.Task{ID: 1, Title: "a"}),
+		"other field":         encodeTaskCursor(taskSort{Field: "status"}, model.Task{ID: 1, Status: "a"}),
+		"wrong value type":    encode(`{"s":"title","v":12,"id":1}`),
+		"padded base64":       base64.URLEncoding.EncodeToString([]byte(`{"s":"title","v":"a","id":1}`)),
+		"wrong ID type":       encode(`{"s":"title","v":"a","id":"1"}`),
+		"negative number key": encode(`{"s":"title","v":"a","id":-1}`),
+	}
+	for name, raw := range tests {
+		if cursor, err := decodeTaskCursor(raw, title); err == nil {
+			t.Errorf("%s: decoded %+v, want an error", name, cursor)
+		}
+	}
+
+	numeric := taskSort{Field: "assigned_to"}
+	if _, err := decodeTaskCursor(encode(`{"s":"assigned_to","v":"7","id":1}`), numeric); err == nil {
+		t.Error("string value accepted for a numeric sort")
+	}
+	timed := taskSort{Field: "due_date"}
+	if _, err := decodeTaskCursor(encode(`{"s":"due_date","v":"yesterday","id":1}`), timed); err == nil {
+		t.Error("malformed time accepted for a time sort")
+	}
+}
+
+func TestTaskSortApply(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	due := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		sort   taskSort
+		cursor *taskCursor
+		where  string
+		order  string
+	}{
+		{
+			sort:  taskSort{Field: "id"},
+			order: "ORDER BY tasks.id ASC",
+		},
+		{
+			sort:   taskSort{Field: "id", Desc: true},
+			cursor: &taskCursor{ID: 9},
+			where:  "WHERE tasks.id < $1",
+			order:  "ORDER BY tasks.id DESC",
+		},
+		{
+			sort:   taskSort{Field: "due_date"},
+			cursor: &taskCursor{ID: 9, value: due},
+			where:  "WHERE ((tasks.due_date > $1 OR (tasks.due_date = $2 AND tasks.id > $3) OR tasks.due_date IS NULL))",
+			order:  "ORDER BY tasks.due_date ASC NULLS LAST, tasks.id ASC",
+		},
+		{
+			sort:   taskSort{Field: "due_date", Desc: true},
+			cursor: &taskCursor{ID: 9},
+			where:  "WHERE (tasks.due_date IS NULL AND tasks.id < $1)",
+			order:  "ORDER BY tasks.due_date DESC NULLS LAST, tasks.id DESC",
+		},
+	}
+	for _, tt := range tests {
+		stmt := tt.sort.apply(db.Model(&model.Task{}), tt.cursor).Find(&[]model.Task{}).Statement
+		sql := stmt.SQL.String()
+		if !strings.Contains(sql, tt.where) || !strings.HasSuffix(sql, tt.order) {
+			t.Errorf("%s with cursor %+v:\n%s\nwant %q and %q", tt.sort, tt.cursor, sql, tt.where, tt.order)
+		}
+	}
+}
+
+func TestParseDateParam(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want time.Time
+		err  bool
+	}{
+		{"2024-01-31", time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), false},
+		{"2024-01-31T10:00:00Z", time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC), false},
+		{"2024-01-31T12:00:00+02:00", time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC), false},
+		{"31/01/2024", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDateParam(tt.raw)
+		if (err != nil) != tt.err || !got.Equal(tt.want) {
+			t.Errorf("parseDateParam(%q) = %v, %v, want %v, error %t", tt.raw, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestSplitParam(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"", nil},
+		{"high", []string{"high"}},
+		{"high, urgent ,,low", []string{"high", "urgent", "low"}},
+		{" , ", nil},
+	}
+	for _, tt := range tests {
+		if got := splitParam(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitParam(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestApplyTaskPatch(t *testing.T) {
+	due := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+	newDue := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	base := func() model.Task {
+		return model.Task{
+			ID:          1,
+			Title:       "Write report",
+			Description: "Quarterly numbers",
+			Status:      "pending",
+			Priority:    "high",
+			DueDate:     &due,
+			AssignedTo:  2,
+			CreatedBy:   3,
+		}
+	}
+
+	tests := []struct {
+		name  string
+		patch string
+		want  func(*model.Task)
+		err   string
+	}{
+		{
+			name:  "empty patch changes nothing",
+			patch: `{}`,
+			want:  func(*model.Task) {},
+		},
+		{
+			name:  "sets given fields only",
+			patch: `{"title": "Write summary", "status": "in_progress"}`,
+			want: func(task *model.Task) {
+				task.Title = "Write summary"
+				task.Status = "in_progress"
+			},
+		},
+		{
+			name:  "null clears optional fields",
+			patch: `{"description": null, "due_date": null, "assigned_to": null, "priority": null}`,
+			want: func(task *model.Task) {
+				task.Description = ""
+				task.DueDate = nil
+				task.AssignedTo = 0
+				task.Priority = ""
+			},
+		},
+		{
+			name:  "sets a due date and assignee",
+			patch: `{"due_date": "2024-02-29T00:00:00Z", "assigned_to": 9}`,
+			want: func(task *model.Task) {
+				task.DueDate = &newDue
+				task.AssignedTo = 9
+			},
+		},
+		{name: "title cannot be cleared", patch: `{"title": null}`, err: "title cannot be cleared"},
+		{name: "title cannot be empty", patch: `{"title": ""}`, err: "title cannot be empty"},
+		{name: "read-only field", patch: `{"created_by": 4}`, err: "unknown or read-only field: created_by"},
+		{name: "unknown field", patch: `{"colour": "red"}`, err: "unknown or read-only field: colour"},
+		{name: "wrong type", patch: `{"assigned_to": "bob"}`, err: "invalid value for assigned_to"},
+		{name: "bad date", patch: `{"due_date": "tomorrow"}`, err: "invalid value for due_date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatalf("invalid patch: %v", err)
+			}
+
+			task := base()
+			err := applyTaskPatch(&task, patch)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := base()
+			tt.want(&want)
+			if !reflect.DeepEqual(task, want) {
+				t.Errorf("task = %+v, want %+v", task, want)
+			}
+		})
+	}
+}
+
+// newTask creates a task as user through the API.
+func newTask(t *testing.T, user model.User, body map[string]interface{}) model.Task {
+	t.Helper()
+	rec := serve(CreateTask, as(request(t, http.MethodPost, "/api/tasks", body), user.ID, "session"))
+	expect(t, rec, http.StatusCreated)
+	var response struct {
+		Task model.Task `json:"task"`
+	}
+	decode(t, rec, &response)
+	return response.Task
+}
+
+// taskRequest builds a request as user for the task named in the path.
+func taskRequest(t *testing.T, method string, user model.User, taskID uint, body interface{}) *http.Request {
+	t.Helper()
+	id := strconv.FormatUint(uint64(taskID), 10)
+	r := as(request(t, method, "/api/tasks/"+id, body), user.ID, "session")
+	r.SetPathValue("id", id)
+	return r
+}
+
+func TestTaskAssignee(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	member := newUser(t, "Bob", "bob@example.com")
+	stranger := newUser(t, "Eve", "eve@example.com")
+	addMember(t, newWorkspace(t, owner), member, model.WorkspaceRoleMember)
+
+	assign := func(assignee uint) int {
+		body := map[string]interface{}{"title": "Write report", "assigned_to": assignee}
+		return serve(CreateTask, as(request(t, http.MethodPost, "/api/tasks", body), owner.ID, "session")).Code
+	}
+	if status := assign(stranger.ID); status != http.StatusBadRequest {
+		t.Errorf("assigning a stranger: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if status := assign(stranger.ID + 100); status != http.StatusBadRequest {
+		t.Errorf("assigning an unknown user: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report", "assigned_to": member.ID})
+	expect(t, serve(PatchTask, taskRequest(t, http.MethodPatch, owner, task.ID, map[string]uint{"assigned_to": stranger.ID})), http.StatusBadRequest)
+	put := map[string]interface{}{"title": "Write report", "assigned_to": stranger.ID}
+	expect(t, serve(UpdateTask, taskRequest(t, http.MethodPut, owner, task.ID, put)), http.StatusBadRequest)
+
+	var notified int64
+	database.DB.Model(&model.Notification{}).Where("user_id = ?", stranger.ID).Count(&notified)
+	if notified != 0 || watchers(t, task)[stranger.ID] {
+		t.Error("a rejected assignee was notified or made a watcher")
+	}
+
+	// Taking the task or leaving it unassigned is always allowed.
+	expect(t, serve(PatchTask, taskRequest(t, http.MethodPatch, owner, task.ID, map[string]uint{"assigned_to": owner.ID})), http.StatusOK)
+	expect(t, serve(PatchTask, taskRequest(t, http.MethodPatch, owner, task.ID, map[string]interface{}{"assigned_to": nil})), http.StatusOK)
+}
+
+func TestTrashAndRestoreTask(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	other := newUser(t, "Bob", "bob@example.com")
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report"})
+
+	expect(t, serve(DeleteTask, taskRequest(t, http.MethodDelete, other, task.ID, nil)), http.StatusNotFound)
+	expect(t, serve(DeleteTask, taskRequest(t, http.MethodDelete, owner, task.ID, nil)), http.StatusOK)
+	expect(t, serve(GetTaskByID, taskRequest(t, http.MethodGet, owner, task.ID, nil)), http.StatusNotFound)
+
+	trash := func(user model.User) []model.Task {
+		rec := serve(GetTrashedTasks, as(request(t, http.MethodGet, "/api/tasks/trash", nil), user.ID, "session"))
+		expect(t, rec, http.StatusOK)
+		var response struct {
+			Tasks []model.Task `json:"tasks"`
+		}
+		decode(t, rec, &response)
+		return response.Tasks
+	}
+	if tasks := trash(owner); len(tasks) != 1 || tasks[0].ID != task.ID {
+		t.Errorf("owner's trash = %+v, want the deleted task", tasks)
+	}
+	if tasks := trash(other); len(tasks) != 0 {
+		t.Errorf("another user's trash = %+v, want it empty", tasks)
+	}
+
+	expect(t, serve(RestoreTask, taskRequest(t, http.MethodPost, other, task.ID, nil)), http.StatusNotFound)
+	rec := serve(RestoreTask, taskRequest(t, http.MethodPost, owner, task.ID, nil))
+	expect(t, rec, http.StatusOK)
+	var restored struct {
+		Task model.Task `json:"task"`
+	}
+	decode(t, rec, &restored)
+	if restored.Task.Version != task.Version+2 {
+		t.Errorf("version = %d, want %d after deleting and restoring", restored.Task.Version, task.Version+2)
+	}
+
+	expect(t, serve(GetTaskByID, taskRequest(t, http.MethodGet, owner, task.ID, nil)), http.StatusOK)
+	expect(t, serve(RestoreTask, taskRequest(t, http.MethodPost, owner, task.ID, nil)), http.StatusNotFound)
+
+	var actions []string
+	database.DB.Model(&model.TaskHistory{}).Where("task_id = ?", task.ID).Order("id").Pluck("action", &actions)
+	want := []string{model.TaskActionCreated, model.TaskActionDeleted, model.TaskActionRestored}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("history = %v, want %v", actions, want)
+	}
+}
+
+func TestPurgeTrashedTasks(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("TASK_TRASH_RETENTION", "720h")
+	owner := newUser(t, "Ada", "ada@example.com")
+
+	expired := newTask(t, owner, map[string]interface{}{"title": "Old"})
+	recent := newTask(t, owner, map[string]interface{}{"title": "New"})
+	kept := newTask(t, owner, map[string]interface{}{"title": "Live"})
+	create(t, &model.Comment{TaskID: expired.ID, AuthorID: owner.ID, Body: "done?"})
+	database.DB.Model(&model.Task{}).Where("id = ?", expired.ID).Update("deleted_at", time.Now().AddDate(0, 0, -31))
+	database.DB.Model(&model.Task{}).Where("id = ?", recent.ID).Update("deleted_at", time.Now().AddDate(0, 0, -29))
+
+	PurgeTrashedTasks()
+
+	exists := func(id uint) bool {
+		var count int64
+		database.DB.Unscoped().Model(&model.Task{}).Where("id = ?", id).Count(&count)
+		return count > 0
+	}
+	if exists(expired.ID) {
+		t.Error("task past the retention period was not purged")
+	}
+	if !exists(recent.ID) || !exists(kept.ID) {
+		t.Error("a task within the retention period was purged")
+	}
+
+	var count int64
+	database.DB.Unscoped().Model(&model.Comment{}).Where("task_id = ?", expired.ID).Count(&count)
+	if count != 0 {
+		t.Error("comments of the purged task remain")
+	}
+	database.DB.Model(&model.TaskWatcher{}).Where("task_id = ?", expired.ID).Count(&count)
+	if count != 0 {
+		t.Error("watchers of the purged task remain")
+	}
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/websocket"
+	"gorm.io/gorm"
+)
+
+func apiTokenResponse(token model.APIToken) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           token.ID,
+		"name":         token.Name,
+		"prefix":       token.Prefix,
+		"scopes":       token.ScopeList(),
+		"expires_at":   token.ExpiresAt,
+		"last_used_at": token.LastUsedAt,
+		"created_at":   token.CreatedAt,
+	}
+}
+
+func CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input model.APITokenInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" || len(input.Scopes) == 0 {
+		http.Error(w, "Name and scopes are required", http.StatusBadRequest)
+		return
+	}
+
+	for _, scope := range input.Scopes {
+		if !slices.Contains(model.ValidScopes, scope) {
+			http.Error(w, "Unknown scope: "+scope, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if input.ExpiresInDays < 0 {
+		http.Error(w, "Expiry must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	plaintext, err := middleware.GenerateAPIToken()
+	if err != nil {
+		http.Error(w, "Could not generate token", http.StatusInternalServerError)
+		return
+	}
+
+	token := model.APIToken{
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    plaintext[:len(middleware.APITokenPrefix)+6],
+		TokenHash: middleware.HashToken(plaintext),
+		Scopes:    strings.Join(slices.Compact(slices.Sorted(slices.Values(input.Scopes))), ","),
+	}
+	if input.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := database.DB.Create(&token).Error; err != nil {
+		http.Error(w, "Could not create token", http.StatusInternalServerError)
+		return
+	}
+
+	// The plaintext token is only ever returned here.
+	response := apiTokenResponse(token)
+	response["token"] = plaintext
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"token": response})
+}
+
+func GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var tokens []model.APIToken
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		http.Error(w, "Could not retrieve tokens", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]map[string]interface{}, 0, len(tokens))
+	for _, token := range tokens {
+		response = append(response, apiTokenResponse(token))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tokens": response})
+}
+
+func DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokenID := r.PathValue("id")
+
+	var token model.APIToken
+	if err := database.DB.Where("id = ? AND user_id = ?", tokenID, userID).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := database.DB.Delete(&token).Error; err != nil {
+		http.Error(w, "Could not revoke token", http.StatusInternalServerError)
+		return
+	}
+	websocket.DisconnectToken(userID, token.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Token revoked successfully"})
+}
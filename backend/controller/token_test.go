@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestCreateAPIToken(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+
+	rec := serve(CreateAPIToken, as(request(t, http.MethodPost, "/api/tokens", map[string]interface{}{
+		"name":            "CI",
+		"scopes":          []string{model.ScopeTasksWrite, model.ScopeTasksRead, model.ScopeTasksRead},
+		"expires_in_days": 30,
+	}), user.ID, "session"))
+	expect(t, rec, http.StatusCreated)
+
+	var created struct {
+		Token struct {
+			ID     uint     `json:"id"`
+			Token  string   `json:"token"`
+			Prefix string   `json:"prefix"`
+			Scopes []string `json:"scopes"`
+		} `json:"token"`
+	}
+	decode(t, rec, &created)
+
+	if !strings.HasPrefix(created.Token.Token, created.Token.Prefix) {
+		t.Errorf("prefix %q is not the start of the token", created.Token.Prefix)
+	}
+	if got := strings.Join(created.Token.Scopes, ","); got != "tasks:read,tasks:write" {
+		t.Errorf("scopes = %s, want them sorted and deduplicated", got)
+	}
+
+	var stored model.APIToken
+	if err := database.DB.First(&stored, created.Token.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.TokenHash != middleware.HashToken(created.Token.Token) {
+		t.Error("stored hash does not match the returned token")
+	}
+	if stored.TokenHash == created.Token.Token || stored.ExpiresAt == nil {
+		t.Errorf("stored token = %+v", stored)
+	}
+
+	// The plaintext is never listed again.
+	rec = serve(GetAPITokens, as(request(t, http.MethodGet, "/api/tokens", nil), user.ID, "session"))
+	expect(t, rec, http.StatusOK)
+	if strings.Contains(rec.Body.String(), created.Token.Token) || strings.Contains(rec.Body.String(), stored.TokenHash) {
+		t.Error("token list exposes the token")
+	}
+}
+
+func TestCreateAPITokenRejectsBadInput(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+
+	for _, body := range []map[string]interface{}{
+		{"name": "CI", "scopes": []string{"tasks:delete"}},
+		{"name": "CI", "scopes": []string{}},
+		{"name": " ", "scopes": []string{model.ScopeTasksRead}},
+		{"name": "CI", "scopes": []string{model.ScopeTasksRead}, "expires_in_days": -1},
+	} {
+		rec := serve(CreateAPIToken, as(request(t, http.MethodPost, "/api/tokens", body), user.ID, "session"))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: status = %d, want %d", body, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestDeleteAPIToken(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	other := newUser(t, "Bob", "bob@example.com")
+
+	token := model.APIToken{UserID: owner.ID, Name: "CI", TokenHash: middleware.HashToken("zkt_test"), Scopes: model.ScopeTasksRead}
+	if err := database.DB.Create(&token).Error; err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.FormatUint(uint64(token.ID), 10)
+
+	deleteAs := func(userID uint) int {
+		r := as(request(t, http.MethodDelete, "/api/tokens/"+id, nil), userID, "session")
+		r.SetPathValue("id", id)
+		return serve(DeleteAPIToken, r).Code
+	}
+
+	if status := deleteAs(other.ID); status != http.StatusNotFound {
+		t.Errorf("deleting another user's token: status = %d, want %d", status, http.StatusNotFound)
+	}
+	if status := deleteAs(owner.ID); status != http.StatusOK {
+		t.Errorf("deleting own token: status = %d, want %d", status, http.StatusOK)
+	}
+
+	var count int64
+	database.DB.Model(&model.APIToken{}).Where("id = ?", token.ID).Count(&count)
+	if count != 0 {
+		t.Error("token still exists after deletion")
+	}
+}
+
+func TestAPITokenUsageIsThrottled(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+
+	token := model.APIToken{UserID: owner.ID, Name: "CI", TokenHash: middleware.HashToken("zkt_test"), Scopes: model.ScopeTasksRead}
+	if err := database.DB.Create(&token).Error; err != nil {
+		t.Fatal(err)
+	}
+	lastUsed := func() *time.Time {
+		var stored model.APIToken
+		database.DB.First(&stored, token.ID)
+		return stored.LastUsedAt
+	}
+
+	if !authenticates(t, "zkt_test") {
+		t.Fatal("the token was rejected")
+	}
+	first := lastUsed()
+	if first == nil {
+		t.Fatal("first use was not recorded")
+	}
+
+	// Busy tokens are not written on every request.
+	if !authenticates(t, "zkt_test") {
+		t.Fatal("the token was rejected")
+	}
+	if again := lastUsed(); again == nil || !again.Equal(*first) {
+		t.Errorf("last used = %v right after %v, want it unchanged", again, first)
+	}
+
+	database.DB.Model(&token).Update("last_used_at", time.Now().Add(-time.Hour))
+	if !authenticates(t, "zkt_test") {
+		t.Fatal("the token was rejected")
+	}
+	if later := lastUsed(); later == nil || time.Since(*later) > time.Minute {
+		t.Errorf("last used = %v an hour later, want it recorded", later)
+	}
+}
@@ -0,0 +1,398 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/mailer"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const emailVerificationTTL = 24 * time.Hour
+
+// appURL builds a link into the frontend, used in emails.
+func appURL(path string) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:3000"
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
+func userResponse(user model.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                    user.ID,
+		"name":                  user.Name,
+		"email":                 user.Email,
+		"email_verified":        user.EmailVerified,
+		"pending_email":         user.PendingEmail,
+		"preferences":           user.Preferences,
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+	}
+}
+
+func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": userResponse(user)})
+}
+
+// validName reports whether a display name is free of control characters.
+// Names end up in emails and logs, where line breaks could forge content.
+func validName(name string) bool {
+	return !strings.ContainsFunc(name, unicode.IsControl)
+}
+
+// cleanName drops the control characters from a name supplied by a trusted
+// source, such as an identity provider.
+func cleanName(name string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+}
+
+func UpdateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input model.ProfileUpdateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			http.Error(w, "Name cannot be empty", http.StatusBadRequest)
+			return
+		}
+		if !validName(name) {
+			http.Error(w, "Name cannot contain control characters", http.StatusBadRequest)
+			return
+		}
+		user.Name = name
+	}
+
+	if prefs := input.Preferences; prefs != nil {
+		if prefs.DarkMode != nil {
+			user.Preferences.DarkMode = *prefs.DarkMode
+		}
+		if prefs.InAppNotifications != nil {
+			user.Preferences.InAppNotifications = *prefs.InAppNotifications
+		}
+		if prefs.EmailNotifications != nil {
+			user.Preferences.EmailNotifications = *prefs.EmailNotifications
+		}
+		if prefs.Language != nil {
+			user.Preferences.Language = *prefs.Language
+		}
+	}
+
+	// A new email only takes effect once the link sent to it is followed.
+	var verificationToken string
+	if input.Email != nil && !strings.EqualFold(strings.TrimSpace(*input.Email), user.Email) {
+		address, err := mail.ParseAddress(strings.TrimSpace(*input.Email))
+		if err != nil {
+			http.Error(w, "Invalid email address", http.StatusBadRequest)
+			return
+		}
+
+		var count int64
+		database.DB.Model(&model.User{}).Where("LOWER(email) = LOWER(?)", address.Address).Count(&count)
+		if count > 0 {
+			http.Error(w, "Email already in use", http.StatusConflict)
+			return
+		}
+
+		verificationToken, err = randomString(32)
+		if err != nil {
+			http.Error(w, "Could not start email verification", http.StatusInternalServerError)
+			return
+		}
+		user.PendingEmail = address.Address
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("Name", "PendingEmail", "Preferences").Save(&user).Error; err != nil {
+			return err
+		}
+		if verificationToken == "" {
+			return nil
+		}
+
+		// Only the most recent pending email can be confirmed.
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&model.EmailVerification{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.EmailVerification{
+			UserID:    user.ID,
+			Email:     user.PendingEmail,
+			TokenHash: middleware.HashToken(verificationToken),
+			ExpiresAt: time.Now().Add(emailVerificationTTL),
+		}).Error
+	})
+	if err != nil {
+		http.Error(w, "Could not update profile", http.StatusInternalServerError)
+		return
+	}
+
+	if verificationToken != "" {
+		mailer.SendAsync(user.PendingEmail, "Confirm your new email address", fmt.Sprintf(
+			"Hi %s,\n\nConfirm this address for your account by opening:\n%s\n\nThe link expires in 24 hours.",
+			user.Name, appURL("/verify-email?token="+verificationToken)))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": userResponse(user)})
+}
+
+// RequestEmailVerification emails a verification link for the user's current
+// address, for accounts that registered with a password and never verified it.
+func RequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if user.EmailVerified {
+		http.Error(w, "Email is already verified", http.StatusConflict)
+		return
+	}
+
+	token, err := randomString(32)
+	if err != nil {
+		http.Error(w, "Could not start email verification", http.StatusInternalServerError)
+		return
+	}
+
+	err = database.DB.Create(&model.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: middleware.HashToken(token),
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	}).Error
+	if err != nil {
+		http.Error(w, "Could not start email verification", http.StatusInternalServerError)
+		return
+	}
+
+	mailer.SendAsync(user.Email, "Confirm your email address", fmt.Sprintf(
+		"Hi %s,\n\nConfirm this address for your account by opening:\n%s\n\nThe link expires in 24 hours.",
+		user.Name, appURL("/verify-email?token="+token)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Verification email sent"})
+}
+
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var input model.VerifyEmailInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Token == "" {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	var user model.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var verification model.EmailVerification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", middleware.HashToken(input.Token), time.Now()).
+			First(&verification).Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		tx.Model(&model.User{}).Where("LOWER(email) = LOWER(?) AND id <> ?", verification.Email, verification.UserID).Count(&count)
+		if count > 0 {
+			return gorm.ErrDuplicatedKey
+		}
+
+		if err := tx.First(&user, verification.UserID).Error; err != nil {
+			return err
+		}
+		// A link for the current address must not undo a later email change.
+		confirmsPending := strings.EqualFold(verification.Email, user.PendingEmail)
+		if !confirmsPending && !strings.EqualFold(verification.Email, user.Email) {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Model(&verification).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		user.Email = verification.Email
+		user.EmailVerified = true
+		if confirmsPending {
+			user.PendingEmail = ""
+		}
+		return tx.Select("Email", "EmailVerified", "PendingEmail").Save(&user).Error
+	})
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			http.Error(w, "Invalid or expired verification link", http.StatusBadRequest)
+		case gorm.ErrDuplicatedKey:
+			http.Error(w, "Email already in use", http.StatusConflict)
+		default:
+			http.Error(w, "Could not verify email", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": userResponse(user)})
+}
+
+func ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input model.PasswordChangeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	if len(input.NewPassword) < 6 {
+		http.Error(w, "New password must be at least 6 characters", http.StatusBadRequest)
+		return
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if user.Password == "" {
+		http.Error(w, "This account signs in with single sign-on and has no password", http.StatusBadRequest)
+		return
+	}
+
+	if !middleware.CheckPasswordHash(input.CurrentPassword, user.Password) {
+		http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
+		return
+	}
+
+	hashedPassword, err := middleware.HashPassword(input.NewPassword)
+	if err != nil {
+		http.Error(w, "Could not hash password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := database.DB.Model(&user).Update("password", hashedPassword).Error; err != nil {
+		http.Error(w, "Could not update password", http.StatusInternalServerError)
+		return
+	}
+
+	sessionID, _ := r.Context().Value(middleware.SessionIDKey).(string)
+	if err := revokeOtherSessions(user.ID, sessionID); err != nil {
+		log.Printf("Could not revoke sessions for user %d: %v", user.ID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Password changed successfully"})
+}
+
+// ResetPassword completes a password reset from an emailed link. Every
+// session is revoked and any login lockout is lifted.
+func ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input model.ResetPasswordInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Token == "" {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	if len(input.NewPassword) < 6 {
+		http.Error(w, "New password must be at least 6 characters", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := middleware.HashPassword(input.NewPassword)
+	if err != nil {
+		http.Error(w, "Could not hash password", http.StatusInternalServerError)
+		return
+	}
+
+	var reset model.PasswordReset
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", middleware.HashToken(input.Token), time.Now()).
+			First(&reset).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&reset).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", reset.UserID).Updates(map[string]interface{}{
+			"password":                hashedPassword,
+			"password_reset_required": false,
+			"failed_login_attempts":   0,
+			"last_failed_login_at":    nil,
+			"locked_until":            nil,
+		}).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Invalid or expired reset link", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Could not reset password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := revokeOtherSessions(reset.UserID, ""); err != nil {
+		log.Printf("Could not revoke sessions for user %d: %v", reset.UserID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Password reset successfully"})
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+// knownVerificationToken replaces the emailed token of the user's open
+// verification with one the test knows.
+func knownVerificationToken(t *testing.T, userID uint) string {
+	t.Helper()
+	token := "verification-" + randomTestString(t)
+	result := database.DB.Model(&model.EmailVerification{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("token_hash", middleware.HashToken(token))
+	if result.Error != nil || result.RowsAffected != 1 {
+		t.Fatalf("expected one open verification, updated %d: %v", result.RowsAffected, result.Error)
+	}
+	return token
+}
+
+func verifyEmail(t *testing.T, token string) int {
+	t.Helper()
+	return serve(VerifyEmail, request(t, http.MethodPost, "/api/auth/verify-email", map[string]string{"token": token})).Code
+}
+
+func changeEmail(t *testing.T, user model.User, email string) int {
+	t.Helper()
+	r := as(request(t, http.MethodPatch, "/api/users/me", map[string]string{"email": email}), user.ID, "session")
+	return serve(UpdateCurrentUser, r).Code
+}
+
+func TestEmailChangeNeedsVerification(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+
+	if status := changeEmail(t, user, "ada@old.example.com"); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if status := changeEmail(t, user, "ada@new.example.com"); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	var stored model.User
+	database.DB.First(&stored, user.ID)
+	if stored.Email != "ada@example.com" || stored.PendingEmail != "ada@new.example.com" {
+		t.Fatalf("email = %q, pending = %q; the change must wait for verification", stored.Email, stored.PendingEmail)
+	}
+
+	// Only the latest requested address can be confirmed.
+	var verification model.EmailVerification
+	database.DB.Where("user_id = ? AND used_at IS NULL", user.ID).First(&verification)
+	if verification.Email != "ada@new.example.com" {
+		t.Errorf("open verification is for %q", verification.Email)
+	}
+
+	token := knownVerificationToken(t, user.ID)
+	if status := verifyEmail(t, token); status != http.StatusOK {
+		t.Fatalf("verify: status = %d, want %d", status, http.StatusOK)
+	}
+	database.DB.First(&stored, user.ID)
+	if stored.Email != "ada@new.example.com" || stored.PendingEmail != "" || !stored.EmailVerified {
+		t.Errorf("after verification: %+v", stored)
+	}
+
+	if status := verifyEmail(t, token); status != http.StatusBadRequest {
+		t.Errorf("reused token: status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestEmailChangeRejectsAddressInUse(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+	newUser(t, "Bob", "bob@example.com")
+
+	if status := changeEmail(t, user, "BOB@example.com"); status != http.StatusConflict {
+		t.Errorf("status = %d, want %d", status, http.StatusConflict)
+	}
+	if status := changeEmail(t, user, "not an address"); status != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestVerifyEmailRejectsAddressTakenSinceRequest(t *testing.T) {
+	dbtest.Open(t)
+	user := newUser(t, "Ada", "ada@example.com")
+
+	if status := changeEmail(t, user, "shared@example.com"); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	token := knownVerificationToken(t, user.ID)
+	newUser(t, "Bob", "Shared@example.com")
+
+	if status := verifyEmail(t, token); status != http.StatusConflict {
+		t.Errorf("status = %d, want %d", status, http.StatusConflict)
+	}
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	hash, err := middleware.HashPassword("old password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := newUser(t, "Ada", "ada@example.com")
+	database.DB.Model(&user).Update("password", hash)
+
+	current, currentToken := login(t, user)
+	_, otherToken := login(t, user)
+
+	change := func(currentPassword string) int {
+		body := map[string]string{"current_password": currentPassword, "new_password": "new password"}
+		return serve(ChangePassword, as(request(t, http.MethodPost, "/api/users/me/password", body), user.ID, current)).Code
+	}
+
+	if status := change("wrong password"); status != http.StatusUnauthorized {
+		t.Errorf("wrong password: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if !authenticates(t, otherToken) {
+		t.Error("a failed password change revoked sessions")
+	}
+
+	if status := change("old password"); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if authenticates(t, otherToken) {
+		t.Error("other sessions survived the password change")
+	}
+	if !authenticates(t, currentToken) {
+		t.Error("the session that changed the password was revoked")
+	}
+
+	var stored model.User
+	database.DB.First(&stored, user.ID)
+	if !middleware.CheckPasswordHash("new password", stored.Password) {
+		t.Error("the new password was not stored")
+	}
+}
+
+func TestEmailIsCaseInsensitive(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	register := map[string]string{"name": "Ada", "email": "Ada@Example.com", "password": "secret password"}
+	expect(t, serve(RegisterUser, request(t, http.MethodPost, "/api/auth/register", register)), http.StatusCreated)
+
+	register["email"] = "ada@example.com"
+	expect(t, serve(RegisterUser, request(t, http.MethodPost, "/api/auth/register", register)), http.StatusConflict)
+
+	login := map[string]string{"email": " ADA@example.COM ", "password": "secret password"}
+	expect(t, serve(LoginUser, request(t, http.MethodPost, "/api/auth/login", login)), http.StatusOK)
+}
@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/filter"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+)
+
+// visibleViews limits a saved view query to the user's own views and those
+// shared in workspaces they belong to.
+func visibleViews(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		memberships := database.DB.Model(&model.WorkspaceMember{}).Select("workspace_id").Where("user_id = ?", userID)
+		return db.Where("owner_id = ? OR workspace_id IN (?)", userID, memberships)
+	}
+}
+
+// loadView loads the saved view named in the path and writes an error
+// response if the user cannot see it.
+func loadView(w http.ResponseWriter, r *http.Request, userID uint) (*model.SavedView, bool) {
+	var view model.SavedView
+	if err := database.DB.Scopes(visibleViews(userID)).Where("id = ?", r.PathValue("id")).First(&view).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "View not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &view, true
+}
+
+// canEditView reports whether the user may change or delete the view: its
+// owner can, and so can admins of the workspace it is shared in.
+func canEditView(view *model.SavedView, userID uint) (bool, error) {
+	if view.OwnerID == userID {
+		return true, nil
+	}
+	if view.WorkspaceID == nil {
+		return false, nil
+	}
+	role, err := workspaceRole(*view.WorkspaceID, userID)
+	return canManageWorkspace(role), err
+}
+
+// validateView checks the filter expression and sort of a view.
+func validateView(view *model.SavedView) string {
+	if view.Name == "" {
+		return "Name is required"
+	}
+	if _, err := filter.Parse(view.Filter); err != nil {
+		return "Invalid filter: " + err.Error()
+	}
+	if _, err := parseTaskSort(view.Sort); err != nil {
+		return "Invalid sort: " + err.Error()
+	}
+	return ""
+}
+
+func GetViews(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var views []model.SavedView
+	if err := database.DB.Scopes(visibleViews(userID)).Order("name").Find(&views).Error; err != nil {
+		http.Error(w, "Could not retrieve views", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"views": views})
+}
+
+func CreateView(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input model.SavedViewInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	view := model.SavedView{
+		Name:        strings.TrimSpace(input.Name),
+		Filter:      input.Filter,
+		Sort:        input.Sort,
+		OwnerID:     userID,
+		WorkspaceID: input.WorkspaceID,
+	}
+	if msg := validateView(&view); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if view.WorkspaceID != nil {
+		role, err := workspaceRole(*view.WorkspaceID, userID)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if role == "" {
+			http.Error(w, "Workspace not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	if err := database.DB.Create(&view).Error; err != nil {
+		http.Error(w, "Could not create view", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"view": view})
+}
+
+func UpdateView(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	view, ok := loadView(w, r, userID)
+	if !ok {
+		return
+	}
+
+	allowed, err := canEditView(view, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "You cannot change this view", http.StatusForbidden)
+		return
+	}
+
+	var input model.SavedViewUpdateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	if input.Name != nil {
+		view.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Filter != nil {
+		view.Filter = *input.Filter
+	}
+	if input.Sort != nil {
+		view.Sort = *input.Sort
+	}
+	if msg := validateView(view); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := database.DB.Select("Name", "Filter", "Sort", "UpdatedAt").Save(view).Error; err != nil {
+		http.Error(w, "Could not update view", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"view": view})
+}
+
+func DeleteView(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	view, ok := loadView(w, r, userID)
+	if !ok {
+		return
+	}
+
+	allowed, err := canEditView(view, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "You cannot delete this view", http.StatusForbidden)
+		return
+	}
+
+	if err := database.DB.Delete(view).Error; err != nil {
+		http.Error(w, "Could not delete view", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "View deleted successfully"})
+}
+
+// GetViewTasks evaluates a saved view for the caller. Shared views only ever
+// return tasks the caller can see, and "me" refers to the caller.
+func GetViewTasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	view, ok := loadView(w, r, userID)
+	if !ok {
+		return
+	}
+
+	expr, err := filter.Parse(view.Filter)
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	sort, err := parseTaskSort(view.Sort)
+	if err != nil {
+		http.Error(w, "Invalid sort: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	condition, args := expr.SQL(filter.Env{UserID: userID, Now: time.Now()})
+	query := database.DB.Model(&model.Task{}).Scopes(visibleTasks(userID)).Where(condition, args...)
+
+	writeTaskPage(w, r, query, sort)
+}
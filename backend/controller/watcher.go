@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// watchTask makes users follow a task, ignoring those who already do.
+func watchTask(db *gorm.DB, taskID uint, userIDs ...uint) error {
+	watchers := make([]model.TaskWatcher, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if userID != 0 {
+			watchers = append(watchers, model.TaskWatcher{TaskID: taskID, UserID: userID})
+		}
+	}
+	if len(watchers) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&watchers).Error
+}
+
+// autoWatch is watchTask for follow-ups to a change that is already saved;
+// failures are only logged.
+func autoWatch(taskID uint, userIDs ...uint) {
+	if err := watchTask(database.DB, taskID, userIDs...); err != nil {
+		log.Printf("Could not add watchers to task %d: %v", taskID, err)
+	}
+}
+
+// taskWatchers are the users who hear about activity on a task.
+func taskWatchers(task *model.Task) []uint {
+	var watchers []uint
+	if err := database.DB.Model(&model.TaskWatcher{}).Where("task_id = ?", task.ID).Pluck("user_id", &watchers).Error; err != nil {
+		log.Printf("Could not load watchers of task %d: %v", task.ID, err)
+	}
+	return watchers
+}
+
+func GetTaskWatchers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := loadCommentTask(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var watchers []model.User
+	err := database.DB.Select("users.id", "users.name").
+		Joins("JOIN task_watchers ON task_watchers.user_id = users.id").
+		Where("task_watchers.task_id = ?", task.ID).
+		Order("task_watchers.created_at, users.id").
+		Find(&watchers).Error
+	if err != nil {
+		http.Error(w, "Could not retrieve watchers", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]map[string]interface{}, 0, len(watchers))
+	watching := false
+	for _, watcher := range watchers {
+		watching = watching || watcher.ID == userID
+		response = append(response, map[string]interface{}{"id": watcher.ID, "name": watcher.Name})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"watchers": response, "watching": watching})
+}
+
+// WatchTask follows a task. Anyone who can see a task may follow it; adding
+// someone else, which also lets them read it, is up to its creator and
+// assignee.
+func WatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input model.WatchTaskInput
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input data", http.StatusBadRequest)
+			return
+		}
+	}
+	if input.UserID == 0 {
+		input.UserID = userID
+	}
+
+	scope := visibleTasks(userID)
+	if input.UserID != userID {
+		scope = editableTasks(userID)
+	}
+
+	var task model.Task
+	if err := database.DB.Scopes(scope).Where("tasks.id = ?", r.PathValue("id")).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Watching grants read access, so others can only be added if they share
+	// a workspace with the caller. Unknown users get the same answer.
+	if input.UserID != userID {
+		shared, err := sharesWorkspace(userID, input.UserID)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !shared {
+			http.Error(w, "Only members of your workspaces can be added as watchers", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := watchTask(database.DB, task.ID, input.UserID); err != nil {
+		http.Error(w, "Could not watch task", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"task_id": task.ID, "user_id": input.UserID, "watching": true})
+}
+
+// UnwatchTask stops a user following a task. Users can unfollow themselves;
+// the task's creator and assignee can also remove other watchers.
+func UnwatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	watcherID, err := strconv.ParseUint(r.PathValue("userId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	scope := editableTasks(userID)
+	if uint(watcherID) == userID {
+		scope = visibleTasks(userID)
+	}
+
+	var task model.Task
+	if err := database.DB.Scopes(scope).Where("tasks.id = ?", r.PathValue("id")).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := database.DB.Where("task_id = ? AND user_id = ?", task.ID, watcherID).Delete(&model.TaskWatcher{}).Error; err != nil {
+		http.Error(w, "Could not unwatch task", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"task_id": task.ID, "user_id": watcherID, "watching": false})
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func watchers(t *testing.T, task model.Task) map[uint]bool {
+	t.Helper()
+	watching := make(map[uint]bool)
+	for _, userID := range taskWatchers(&task) {
+		watching[userID] = true
+	}
+	return watching
+}
+
+func watch(t *testing.T, user model.User, task model.Task, watcher model.User) int {
+	t.Helper()
+	return serve(WatchTask, taskRequest(t, http.MethodPost, user, task.ID, map[string]uint{"user_id": watcher.ID})).Code
+}
+
+func unwatch(t *testing.T, user model.User, task model.Task, watcher model.User) int {
+	t.Helper()
+	r := taskRequest(t, http.MethodDelete, user, task.ID, nil)
+	r.SetPathValue("userId", strconv.FormatUint(uint64(watcher.ID), 10))
+	return serve(UnwatchTask, r).Code
+}
+
+func TestParticipantsWatchTasks(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	assignee := newUser(t, "Bob", "bob@example.com")
+	next := newUser(t, "Cy", "cy@example.com")
+	workspace := newWorkspace(t, owner)
+	addMember(t, workspace, assignee, model.WorkspaceRoleMember)
+	addMember(t, workspace, next, model.WorkspaceRoleMember)
+
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report", "assigned_to": assignee.ID})
+	if got := watchers(t, task); len(got) != 2 || !got[owner.ID] || !got[assignee.ID] {
+		t.Fatalf("watchers = %v, want the creator and assignee", got)
+	}
+
+	expect(t, serve(PatchTask, taskRequest(t, http.MethodPatch, owner, task.ID, map[string]uint{"assigned_to": next.ID})), http.StatusOK)
+	if got := watchers(t, task); !got[next.ID] || !got[assignee.ID] {
+		t.Errorf("watchers = %v, want the new assignee added and the old one kept", got)
+	}
+
+	// Commenting follows the task again after unfollowing it.
+	if status := unwatch(t, next, task, next); status != http.StatusOK {
+		t.Fatalf("unwatching: status = %d, want %d", status, http.StatusOK)
+	}
+	expect(t, serve(CreateComment, taskRequest(t, http.MethodPost, next, task.ID, map[string]string{"body": "On it"})), http.StatusCreated)
+	if got := watchers(t, task); !got[next.ID] {
+		t.Errorf("watchers = %v, want the commenter", got)
+	}
+}
+
+func TestWatchTask(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	follower := newUser(t, "Bob", "bob@example.com")
+	stranger := newUser(t, "Eve", "eve@example.com")
+	addMember(t, newWorkspace(t, owner), follower, model.WorkspaceRoleMember)
+	task := newTask(t, owner, map[string]interface{}{"title": "Write report"})
+
+	if status := watch(t, stranger, task, stranger); status != http.StatusNotFound {
+		t.Errorf("stranger watching: status = %d, want %d", status, http.StatusNotFound)
+	}
+	// Users outside the owner's workspaces cannot be made to follow the task,
+	// and unknown users are refused alike.
+	if status := watch(t, owner, task, stranger); status != http.StatusBadRequest {
+		t.Errorf("adding a stranger: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if status := watch(t, owner, task, model.User{ID: stranger.ID + 100}); status != http.StatusBadRequest {
+		t.Errorf("adding an unknown user: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if status := watch(t, owner, task, follower); status != http.StatusOK {
+		t.Fatalf("owner adding a watcher: status = %d, want %d", status, http.StatusOK)
+	}
+
+	// Watching lets the follower read the task, but not manage its watchers.
+	expect(t, serve(GetTaskWatchers, taskRequest(t, http.MethodGet, follower, task.ID, nil)), http.StatusOK)
+	if status := watch(t, follower, task, stranger); status != http.StatusNotFound {
+		t.Errorf("follower adding a watcher: status = %d, want %d", status, http.StatusNotFound)
+	}
+	if status := unwatch(t, follower, task, owner); status != http.StatusNotFound {
+		t.Errorf("follower removing the owner: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	patch := map[string]interface{}{"status": "in_progress"}
+	expect(t, serve(PatchTask, taskRequest(t, http.MethodPatch, owner, task.ID, patch)), http.StatusOK)
+	if types := notificationsOf(t, follower); len(types) != 1 || types[0] != model.NotificationStatusChanged {
+		t.Fatalf("follower notifications = %v, want a status change", types)
+	}
+
+	if status := unwatch(t, follower, task, follower); status != http.StatusOK {
+		t.Fatalf("unwatching: status = %d, want %d", status, http.StatusOK)
+	}
+	patch = map[string]interface{}{"status": "completed"}
+	expect(t, serve(PatchTask, taskRequest(t, http.MethodPatch, owner, task.ID, patch)), http.StatusOK)
+	if types := notificationsOf(t, follower); len(types) != 1 {
+		t.Errorf("follower notifications = %v, want none after unwatching", types)
+	}
+
+	var count int64
+	database.DB.Scopes(visibleTasks(follower.ID)).Model(&model.Task{}).Where("tasks.id = ?", task.ID).Count(&count)
+	if count != 0 {
+		t.Error("the task is still visible to the former follower")
+	}
+}
@@ -0,0 +1,438 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/mailer"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultInvitationTTL = 7 * 24 * time.Hour
+
+var (
+	errInvitationInvalid       = errors.New("invitation is invalid, expired or already used")
+	errInvitationEmailMismatch = errors.New("invitation was sent to a different email address")
+)
+
+// workspaceRole returns the user's role in the workspace, or "" if they are
+// not a member.
+func workspaceRole(workspaceID interface{}, userID uint) (string, error) {
+	var member model.WorkspaceMember
+	err := database.DB.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error
+	if err == gorm.ErrRecordNotFound {
+		return "", nil
+	}
+	return member.Role, err
+}
+
+func canManageWorkspace(role string) bool {
+	return model.WorkspaceRoleRank[role] >= model.WorkspaceRoleRank[model.WorkspaceRoleAdmin]
+}
+
+func CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input model.WorkspaceInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	workspace := model.Workspace{Name: input.Name, OwnerID: userID}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      userID,
+			Role:        model.WorkspaceRoleOwner,
+		}).Error
+	})
+	if err != nil {
+		http.Error(w, "Could not create workspace", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"workspace": workspace})
+}
+
+func GetWorkspaces(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var workspaces []struct {
+		model.Workspace
+		Role string `json:"role"`
+	}
+	err := database.DB.Table("workspaces").
+		Select("workspaces.*, workspace_members.role").
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id").
+		Where("workspace_members.user_id = ?", userID).
+		Order("workspaces.name").
+		Scan(&workspaces).Error
+	if err != nil {
+		http.Error(w, "Could not retrieve workspaces", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"workspaces": workspaces})
+}
+
+func GetWorkspaceMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workspaceID := r.PathValue("id")
+
+	role, err := workspaceRole(workspaceID, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if role == "" {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	var members []struct {
+		ID       uint      `json:"id"`
+		Name     string    `json:"name"`
+		Email    string    `json:"email"`
+		Role     string    `json:"role"`
+		JoinedAt time.Time `json:"joined_at"`
+	}
+	err = database.DB.Table("workspace_members").
+		Select("users.id, users.name, users.email, workspace_members.role, workspace_members.created_at AS joined_at").
+		Joins("JOIN users ON users.id = workspace_members.user_id").
+		Where("workspace_members.workspace_id = ?", workspaceID).
+		Order("users.name").
+		Scan(&members).Error
+	if err != nil {
+		http.Error(w, "Could not retrieve members", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"members": members})
+}
+
+func CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var workspace model.Workspace
+	if err := database.DB.Where("id = ?", r.PathValue("id")).First(&workspace).Error; err != nil {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	role, err := workspaceRole(workspace.ID, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if role == "" {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+	if !canManageWorkspace(role) {
+		http.Error(w, "Only workspace admins can invite", http.StatusForbidden)
+		return
+	}
+
+	var input model.InvitationInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input data", http.StatusBadRequest)
+		return
+	}
+
+	if input.Role == "" {
+		input.Role = model.WorkspaceRoleMember
+	}
+	if input.Role != model.WorkspaceRoleMember && input.Role != model.WorkspaceRoleAdmin {
+		http.Error(w, "Role must be member or admin", http.StatusBadRequest)
+		return
+	}
+
+	if input.Email != "" {
+		address, err := mail.ParseAddress(strings.TrimSpace(input.Email))
+		if err != nil {
+			http.Error(w, "Invalid email address", http.StatusBadRequest)
+			return
+		}
+		input.Email = address.Address
+	}
+
+	ttl := defaultInvitationTTL
+	if input.ExpiresInHours < 0 {
+		http.Error(w, "Expiry must not be negative", http.StatusBadRequest)
+		return
+	}
+	if input.ExpiresInHours > 0 {
+		ttl = time.Duration(input.ExpiresInHours) * time.Hour
+	}
+
+	token, err := randomString(32)
+	if err != nil {
+		http.Error(w, "Could not create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	invitation := model.Invitation{
+		WorkspaceID: workspace.ID,
+		Email:       input.Email,
+		Role:        input.Role,
+		TokenHash:   middleware.HashToken(token),
+		InvitedBy:   userID,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := database.DB.Create(&invitation).Error; err != nil {
+		http.Error(w, "Could not create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	link := appURL("/invite/" + token)
+	if invitation.Email != "" {
+		mailer.SendAsync(invitation.Email, "You have been invited to "+workspace.Name, fmt.Sprintf(
+			"You have been invited to join the workspace %q as %s.\n\nAccept the invitation here:\n%s\n\nThe link expires on %s UTC.",
+			workspace.Name, invitation.Role, link, invitation.ExpiresAt.UTC().Format(time.RFC1123)))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"invitation": invitation,
+		"token":      token,
+		"link":       link,
+	})
+}
+
+func GetInvitations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workspaceID := r.PathValue("id")
+
+	role, err := workspaceRole(workspaceID, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !canManageWorkspace(role) {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	var invitations []model.Invitation
+	err = database.DB.
+		Where("workspace_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", workspaceID, time.Now()).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	if err != nil {
+		http.Error(w, "Could not retrieve invitations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"invitations": invitations})
+}
+
+func RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workspaceID := r.PathValue("id")
+
+	role, err := workspaceRole(workspaceID, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !canManageWorkspace(role) {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	result := database.DB.Model(&model.Invitation{}).
+		Where("id = ? AND workspace_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", r.PathValue("invitationId"), workspaceID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		http.Error(w, "Could not revoke invitation", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Invitation revoked successfully"})
+}
+
+// GetInvitation lets the invite page show what is being accepted before the
+// visitor signs in or registers.
+func GetInvitation(w http.ResponseWriter, r *http.Request) {
+	var invitation model.Invitation
+	err := database.DB.
+		Where("token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?",
+			middleware.HashToken(r.PathValue("token")), time.Now()).
+		First(&invitation).Error
+	if err != nil {
+		http.Error(w, "Invitation not found or expired", http.StatusNotFound)
+		return
+	}
+
+	var workspace model.Workspace
+	var inviter model.User
+	database.DB.First(&workspace, invitation.WorkspaceID)
+	database.DB.First(&inviter, invitation.InvitedBy)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"invitation": map[string]interface{}{
+			"workspace":  map[string]interface{}{"id": workspace.ID, "name": workspace.Name},
+			"invited_by": inviter.Name,
+			"email":      invitation.Email,
+			"role":       invitation.Role,
+			"expires_at": invitation.ExpiresAt,
+		},
+	})
+}
+
+func AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	member, err := acceptInvitation(&user, r.PathValue("token"))
+	if err != nil {
+		switch err {
+		case errInvitationInvalid:
+			http.Error(w, "Invitation not found or expired", http.StatusNotFound)
+		case errInvitationEmailMismatch:
+			http.Error(w, "This invitation was sent to a different email address", http.StatusForbidden)
+		default:
+			http.Error(w, "Could not accept invitation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"membership": member})
+}
+
+// acceptInvitation redeems an invitation token for the user. Tokens are single
+// use; an existing member keeps the higher of their current and invited role.
+func acceptInvitation(user *model.User, token string) (*model.WorkspaceMember, error) {
+	var member model.WorkspaceMember
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var invitation model.Invitation
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?",
+				middleware.HashToken(token), time.Now()).
+			First(&invitation).Error
+		if err == gorm.ErrRecordNotFound {
+			return errInvitationInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		if invitation.Email != "" && !strings.EqualFold(invitation.Email, user.Email) {
+			return errInvitationEmailMismatch
+		}
+
+		now := time.Now()
+		err = tx.Model(&invitation).Updates(map[string]interface{}{
+			"accepted_at": now,
+			"accepted_by": user.ID,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("workspace_id = ? AND user_id = ?", invitation.WorkspaceID, user.ID).First(&member).Error
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			member = model.WorkspaceMember{
+				WorkspaceID: invitation.WorkspaceID,
+				UserID:      user.ID,
+				Role:        invitation.Role,
+			}
+			return tx.Create(&member).Error
+		case err != nil:
+			return err
+		case model.WorkspaceRoleRank[invitation.Role] > model.WorkspaceRoleRank[member.Role]:
+			member.Role = invitation.Role
+			return tx.Model(&member).Update("role", member.Role).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// acceptInvitationOnAuth consumes an invite token passed to register or
+// login. A bad invite never fails the authentication itself; the outcome is
+// reported alongside the token instead.
+func acceptInvitationOnAuth(user *model.User, token string) map[string]interface{} {
+	if token == "" {
+		return nil
+	}
+
+	member, err := acceptInvitation(user, token)
+	if err != nil {
+		return map[string]interface{}{"invitation_error": err.Error()}
+	}
+	return map[string]interface{}{"membership": member}
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+// newWorkspace creates a workspace owned by owner through the API.
+func newWorkspace(t *testing.T, owner model.User) model.Workspace {
+	t.Helper()
+	rec := serve(CreateWorkspace, as(request(t, http.MethodPost, "/api/workspaces", map[string]string{"name": "Team"}), owner.ID, "session"))
+	expect(t, rec, http.StatusCreated)
+	var response struct {
+		Workspace model.Workspace `json:"workspace"`
+	}
+	decode(t, rec, &response)
+	return response.Workspace
+}
+
+func addMember(t *testing.T, workspace model.Workspace, user model.User, role string) {
+	t.Helper()
+	create(t, &model.WorkspaceMember{WorkspaceID: workspace.ID, UserID: user.ID, Role: role})
+}
+
+func memberRole(t *testing.T, workspace model.Workspace, user model.User) string {
+	t.Helper()
+	role, err := workspaceRole(workspace.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return role
+}
+
+// invite creates an invitation as inviter and returns the response.
+func invite(t *testing.T, workspace model.Workspace, inviter model.User, body map[string]interface{}) *createdInvitation {
+	t.Helper()
+	id := strconv.FormatUint(uint64(workspace.ID), 10)
+	r := as(request(t, http.MethodPost, "/api/workspaces/"+id+"/invitations", body), inviter.ID, "session")
+	r.SetPathValue("id", id)
+	rec := serve(CreateInvitation, r)
+	response := &createdInvitation{Status: rec.Code}
+	if rec.Code == http.StatusCreated {
+		decode(t, rec, response)
+	}
+	return response
+}
+
+// createdInvitation is what CreateInvitation responded with.
+type createdInvitation struct {
+	Status     int
+	Token      string           `json:"token"`
+	Invitation model.Invitation `json:"invitation"`
+}
+
+func accept(t *testing.T, user model.User, token string) int {
+	t.Helper()
+	r := as(request(t, http.MethodPost, "/api/invitations/"+token+"/accept", nil), user.ID, "session")
+	r.SetPathValue("token", token)
+	return serve(AcceptInvitation, r).Code
+}
+
+func TestCreateWorkspaceMakesCreatorOwner(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+
+	workspace := newWorkspace(t, owner)
+	if workspace.OwnerID != owner.ID {
+		t.Errorf("owner = %d, want %d", workspace.OwnerID, owner.ID)
+	}
+	if role := memberRole(t, workspace, owner); role != model.WorkspaceRoleOwner {
+		t.Errorf("creator role = %q, want owner", role)
+	}
+}
+
+func TestCreateInvitationPermissions(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	admin := newUser(t, "Bob", "bob@example.com")
+	member := newUser(t, "Cy", "cy@example.com")
+	outsider := newUser(t, "Di", "di@example.com")
+
+	workspace := newWorkspace(t, owner)
+	addMember(t, workspace, admin, model.WorkspaceRoleAdmin)
+	addMember(t, workspace, member, model.WorkspaceRoleMember)
+
+	tests := []struct {
+		name    string
+		inviter model.User
+		body    map[string]interface{}
+		status  int
+	}{
+		{"owner", owner, map[string]interface{}{}, http.StatusCreated},
+		{"admin", admin, map[string]interface{}{"role": model.WorkspaceRoleAdmin}, http.StatusCreated},
+		{"member", member, map[string]interface{}{}, http.StatusForbidden},
+		{"outsider", outsider, map[string]interface{}{}, http.StatusNotFound},
+		{"owner role", owner, map[string]interface{}{"role": model.WorkspaceRoleOwner}, http.StatusBadRequest},
+		{"bad email", owner, map[string]interface{}{"email": "not an address"}, http.StatusBadRequest},
+		{"negative expiry", owner, map[string]interface{}{"expires_in_hours": -1}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if response := invite(t, workspace, tt.inviter, tt.body); response.Status != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, response.Status, tt.status)
+		}
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	invitee := newUser(t, "Bob", "bob@example.com")
+	other := newUser(t, "Cy", "cy@example.com")
+	database.DB.Model(&invitee).Update("email_verified", false)
+
+	workspace := newWorkspace(t, owner)
+	invitation := invite(t, workspace, owner, map[string]interface{}{"email": "BOB@example.com", "role": model.WorkspaceRoleAdmin})
+	if invitation.Status != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", invitation.Status, http.StatusCreated)
+	}
+
+	if status := accept(t, other, invitation.Token); status != http.StatusForbidden {
+		t.Errorf("other address: status = %d, want %d", status, http.StatusForbidden)
+	}
+	if status := accept(t, invitee, invitation.Token); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if role := memberRole(t, workspace, invitee); role != model.WorkspaceRoleAdmin {
+		t.Errorf("role = %q, want admin", role)
+	}
+
+	// Accepting an invitation proves nothing about the address.
+	var stored model.User
+	database.DB.First(&stored, invitee.ID)
+	if stored.EmailVerified {
+		t.Error("accepting the invitation verified the email")
+	}
+
+	if status := accept(t, invitee, invitation.Token); status != http.StatusNotFound {
+		t.Errorf("reused invitation: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestAcceptInvitationKeepsHigherRole(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	admin := newUser(t, "Bob", "bob@example.com")
+
+	workspace := newWorkspace(t, owner)
+	addMember(t, workspace, admin, model.WorkspaceRoleAdmin)
+
+	invitation := invite(t, workspace, owner, map[string]interface{}{"role": model.WorkspaceRoleMember})
+	if status := accept(t, admin, invitation.Token); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if role := memberRole(t, workspace, admin); role != model.WorkspaceRoleAdmin {
+		t.Errorf("role = %q, want admin kept", role)
+	}
+}
+
+func TestRevokedInvitationCannotBeAccepted(t *testing.T) {
+	dbtest.Open(t)
+	owner := newUser(t, "Ada", "ada@example.com")
+	invitee := newUser(t, "Bob", "bob@example.com")
+
+	workspace := newWorkspace(t, owner)
+	invitation := invite(t, workspace, owner, map[string]interface{}{})
+
+	workspaceID := strconv.FormatUint(uint64(workspace.ID), 10)
+	invitationID := strconv.FormatUint(uint64(invitation.Invitation.ID), 10)
+	r := as(request(t, http.MethodDelete, "/api/workspaces/"+workspaceID+"/invitations/"+invitationID, nil), owner.ID, "session")
+	r.SetPathValue("id", workspaceID)
+	r.SetPathValue("invitationId", invitationID)
+	expect(t, serve(RevokeInvitation, r), http.StatusOK)
+
+	if status := accept(t, invitee, invitation.Token); status != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", status, http.StatusNotFound)
+	}
+	if role := memberRole(t, workspace, invitee); role != "" {
+		t.Errorf("role = %q, want no membership", role)
+	}
+}
+
+func TestRegisterWithInvitation(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+	owner := newUser(t, "Ada", "ada@example.com")
+	workspace := newWorkspace(t, owner)
+	invitation := invite(t, workspace, owner, map[string]interface{}{"email": "bob@example.com"})
+
+	body := map[string]string{"name": "Bob", "email": "bob@example.com", "password": "secret password", "invite_token": invitation.Token}
+	rec := serve(RegisterUser, request(t, http.MethodPost, "/api/auth/register", body))
+	expect(t, rec, http.StatusCreated)
+
+	var response struct {
+		User       struct{ ID uint }      `json:"user"`
+		Membership *model.WorkspaceMember `json:"membership"`
+	}
+	decode(t, rec, &response)
+	if response.Membership == nil || response.Membership.WorkspaceID != workspace.ID {
+		t.Fatalf("membership = %+v", response.Membership)
+	}
+
+	var user model.User
+	database.DB.First(&user, response.User.ID)
+	if user.EmailVerified {
+		t.Error("registering through an invitation verified the email")
+	}
+}
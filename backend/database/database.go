@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
 	"gorm.io/driver/postgres"
@@ -29,10 +30,153 @@ func ConnectDB() {
 	}
 	log.Println("Connected to database successfully")
 
-	// Auto Migrate
-	err = DB.AutoMigrate(&model.User{}, &model.Task{})
-	if err != nil {
+	if err := Migrate(DB); err != nil {
 		log.Fatal("Failed to migrate database: ", err)
 	}
 	log.Println("Database migration completed")
+
+	promoteAdmins()
+}
+
+// Migrate brings the schema of db up to date.
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&model.User{},
+		&model.Task{},
+		&model.APIToken{},
+		&model.OIDCIdentity{},
+		&model.OIDCAuthRequest{},
+		&model.LoginAttempt{},
+		&model.Session{},
+		&model.EmailVerification{},
+		&model.AccountDeletionConfirmation{},
+		&model.AISuggestionRecord{},
+		&model.Workspace{},
+		&model.WorkspaceMember{},
+		&model.Invitation{},
+		&model.AdminAuditLog{},
+		&model.PasswordReset{},
+		&model.TaskHistory{},
+		&model.TaskRevision{},
+		&model.SavedView{},
+		&model.Label{},
+		&model.Comment{},
+		&model.CommentEdit{},
+		&model.Notification{},
+		&model.TaskAccess{},
+		&model.DueSoonReminder{},
+		&model.TaskWatcher{},
+		&model.Attachment{},
+		&model.TaskDependency{},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range sqlMigrations {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlMigrations run after AutoMigrate for what GORM cannot express. Each
+// statement must be idempotent.
+var sqlMigrations = []string{
+	// Tasks without a due date used to store Go's zero time rather than NULL,
+	// which would now read as a date in year 1 and count as overdue.
+	`UPDATE tasks SET due_date = NULL WHERE due_date < '0002-01-01 00:00:00+00'`,
+	`UPDATE task_revisions SET due_date = NULL WHERE due_date < '0002-01-01 00:00:00+00'`,
+	`CREATE OR REPLACE FUNCTION reject_task_history_change() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'task history is append-only';
+	END;
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS task_histories_append_only ON task_histories`,
+	`CREATE TRIGGER task_histories_append_only
+		BEFORE UPDATE OR DELETE ON task_histories
+		FOR EACH ROW EXECUTE FUNCTION reject_task_history_change()`,
+	// Full-text search over tasks. The generated column keeps the vector in
+	// sync with the title and description on every insert and update.
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(description, '')), 'B')
+		) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_tasks_search_vector ON tasks USING GIN (search_vector)`,
+	// Data migrations that must only ever run once record their name here.
+	`CREATE TABLE IF NOT EXISTS data_migrations (
+		name text PRIMARY KEY,
+		applied_at timestamptz NOT NULL DEFAULT NOW()
+	)`,
+	// Creators, assignees and commenters of tasks that predate watchers follow
+	// them. It runs once, so later unfollows stick even if nobody is left
+	// watching anything. Databases that got watchers before the migration was
+	// recorded already had it.
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM data_migrations WHERE name = 'backfill_task_watchers') THEN
+			IF NOT EXISTS (SELECT 1 FROM task_watchers) THEN
+				INSERT INTO task_watchers (task_id, user_id, created_at)
+					SELECT task_id, user_id, NOW() FROM (
+						SELECT id AS task_id, created_by AS user_id FROM tasks
+						UNION SELECT id, assigned_to FROM tasks
+						UNION SELECT task_id, author_id FROM comments
+					) AS participants
+					WHERE user_id <> 0
+					ON CONFLICT DO NOTHING;
+			END IF;
+			INSERT INTO data_migrations (name) VALUES ('backfill_task_watchers');
+		END IF;
+	END
+	$$`,
+	// Accounts from before preferences were stored have none, which would
+	// read as every notification channel turned off. They get the defaults
+	// of model.DefaultPreferences.
+	`UPDATE users
+		SET preferences = '{"in_app_notifications": true, "email_notifications": true, "language": "en"}'
+		WHERE preferences IS NULL OR preferences = 'null'::jsonb`,
+	// Addresses are unique regardless of case. Accounts that already share an
+	// address in different case stop the migration, and must be merged or
+	// renamed by hand before the server starts.
+	`DO $$
+	DECLARE
+		duplicates text;
+	BEGIN
+		SELECT string_agg(address, ', ') INTO duplicates FROM (
+			SELECT LOWER(email) AS address FROM users GROUP BY LOWER(email) HAVING COUNT(*) > 1
+		) AS shared;
+		IF duplicates IS NOT NULL THEN
+			RAISE EXCEPTION 'accounts share email addresses that differ only in case: %', duplicates;
+		END IF;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email));
+	END
+	$$`,
+}
+
+// promoteAdmins grants the admin role to the accounts listed in ADMIN_EMAILS,
+// which is how the first administrator is bootstrapped. Only verified
+// addresses count, so registering a listed address is not enough.
+func promoteAdmins() {
+	var emails []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	if len(emails) == 0 {
+		return
+	}
+
+	result := DB.Model(&model.User{}).
+		Where("LOWER(email) IN ? AND email_verified = ? AND role <> ?", emails, true, model.RoleAdmin).
+		Update("role", model.RoleAdmin)
+	if result.Error != nil {
+		log.Printf("Failed to promote admins: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Promoted %d user(s) to admin", result.RowsAffected)
+	}
 }
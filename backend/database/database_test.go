@@ -0,0 +1,159 @@
+package database_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestMigrateRejectsEmailsDifferingInCase(t *testing.T) {
+	db := dbtest.Open(t)
+
+	// Recreate a database from before addresses were unique regardless of case.
+	if err := db.Exec("DROP INDEX idx_users_email_lower").Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"ada@example.com", "Ada@Example.com"} {
+		if err := db.Create(&model.User{Name: "Ada", Email: email}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := database.Migrate(db)
+	if err == nil || !strings.Contains(err.Error(), "ada@example.com") {
+		t.Fatalf("Migrate() = %v, want an error naming the shared address", err)
+	}
+
+	// Once the accounts are told apart the migration goes through.
+	db.Model(&model.User{}).Where("email = ?", "Ada@Example.com").Update("email", "ada+old@example.com")
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrate() = %v", err)
+	}
+	if err := db.Create(&model.User{Name: "Ada", Email: "ADA@example.com"}).Error; err == nil {
+		t.Error("an address differing only in case was accepted")
+	}
+}
+
+func TestPromoteAdminsRequiresVerifiedEmail(t *testing.T) {
+	dbtest.Open(t)
+	t.Setenv("ADMIN_EMAILS", " Ada@example.com, bob@example.com ,")
+
+	users := []model.User{
+		{Name: "Ada", Email: "ada@example.com", EmailVerified: true},
+		{Name: "Bob", Email: "bob@example.com"},
+		{Name: "Cy", Email: "cy@example.com", EmailVerified: true},
+	}
+	for i := range users {
+		if err := database.DB.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	database.PromoteAdmins()
+
+	want := map[string]string{
+		"ada@example.com": model.RoleAdmin,
+		"bob@example.com": model.RoleUser,
+		"cy@example.com":  model.RoleUser,
+	}
+	for _, user := range users {
+		var stored model.User
+		database.DB.First(&stored, user.ID)
+		if stored.Role != want[user.Email] {
+			t.Errorf("%s has role %q, want %q", user.Email, stored.Role, want[user.Email])
+		}
+	}
+}
+
+func TestMigrateFillsMissingPreferences(t *testing.T) {
+	db := dbtest.Open(t)
+
+	user := model.User{Name: "Ada", Email: "ada@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Accounts created before preferences existed have none stored.
+	if err := db.Exec("UPDATE users SET preferences = NULL WHERE id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrate() = %v", err)
+	}
+	var stored model.User
+	db.First(&stored, user.ID)
+	if !reflect.DeepEqual(stored.Preferences, model.DefaultPreferences()) {
+		t.Errorf("preferences = %+v, want the defaults", stored.Preferences)
+	}
+}
+
+func TestMigrateClearsZeroDueDates(t *testing.T) {
+	db := dbtest.Open(t)
+
+	due := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	undated := model.Task{Title: "Undated"}
+	dated := model.Task{Title: "Dated", DueDate: &due}
+	for _, task := range []*model.Task{&undated, &dated} {
+		if err := db.Create(task).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Tasks without a due date used to be stored with the zero time.
+	if err := db.Exec("UPDATE tasks SET due_date = ? WHERE id = ?", time.Time{}, undated.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrate() = %v", err)
+	}
+	var stored model.Task
+	db.First(&stored, undated.ID)
+	if stored.DueDate != nil {
+		t.Errorf("due date = %v, want none", stored.DueDate)
+	}
+	db.First(&stored, dated.ID)
+	if stored.DueDate == nil || !stored.DueDate.Equal(due) {
+		t.Errorf("due date = %v, want %v", stored.DueDate, due)
+	}
+}
+
+func TestMigrateBackfillsWatchersOnce(t *testing.T) {
+	db := dbtest.Open(t)
+
+	user := model.User{Name: "Ada", Email: "ada@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	task := model.Task{Title: "Write report", CreatedBy: user.ID, AssignedTo: user.ID}
+	if err := db.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+	watchers := func() int64 {
+		var count int64
+		db.Model(&model.TaskWatcher{}).Where("task_id = ?", task.ID).Count(&count)
+		return count
+	}
+
+	// A database from before watchers has none and no record of the backfill.
+	db.Exec("DELETE FROM data_migrations")
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrate() = %v", err)
+	}
+	if n := watchers(); n != 1 {
+		t.Fatalf("%d watchers after the backfill, want the creator", n)
+	}
+
+	// Once everybody unfollowed, restarting does not bring them back.
+	db.Exec("DELETE FROM task_watchers")
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrate() = %v", err)
+	}
+	if n := watchers(); n != 0 {
+		t.Errorf("%d watchers after migrating again, want none", n)
+	}
+}
@@ -0,0 +1,3 @@
+package database
+
+var PromoteAdmins = promoteAdmins
@@ -0,0 +1,220 @@
+// Package filter implements the small expression language used by saved
+// views to select tasks, for example:
+//
+//	assigned_to = me and priority in ('high', 'urgent') and overdue
+//	status != 'completed' and (due_date < today+7d or due_date is null)
+//	title contains 'invoice' and not created_by = me
+//
+// Expressions are parsed once, when a view is saved, and compiled into a
+// parameterised SQL condition over the tasks table each time it is used.
+// Only known fields can be referenced and every value is passed as a query
+// argument, so user input never becomes part of the SQL text.
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxLength bounds the size of an expression.
+const MaxLength = 2000
+
+const maxDepth = 32
+
+// Env carries the values that depend on who evaluates an expression and when.
+type Env struct {
+	UserID uint
+	Now    time.Time
+}
+
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindTime
+	kindUser
+	kindBool
+)
+
+type field struct {
+	column string
+	kind   fieldKind
+	// predicate builds the condition for boolean fields.
+	predicate func(env Env) (string, []interface{})
+}
+
+var fields = map[string]field{
+	"title":       {column: "tasks.title", kind: kindString},
+	"description": {column: "tasks.description", kind: kindString},
+	"status":      {column: "tasks.status", kind: kindString},
+	"priority":    {column: "tasks.priority", kind: kindString},
+	"due_date":    {column: "tasks.due_date", kind: kindTime},
+	"created_at":  {column: "tasks.created_at", kind: kindTime},
+	"updated_at":  {column: "tasks.updated_at", kind: kindTime},
+	"assigned_to": {column: "tasks.assigned_to", kind: kindUser},
+	"created_by":  {column: "tasks.created_by", kind: kindUser},
+	"overdue": {kind: kindBool, predicate: func(env Env) (string, []interface{}) {
+		return "(tasks.due_date < ? AND tasks.status <> ?)", []interface{}{env.Now, "completed"}
+	}},
+}
+
+// Expr is a parsed filter expression.
+type Expr interface {
+	// SQL returns a condition for a GORM Where clause and its arguments.
+	SQL(env Env) (string, []interface{})
+}
+
+type binaryExpr struct {
+	op          string // AND or OR
+	left, right Expr
+}
+
+func (e binaryExpr) SQL(env Env) (string, []interface{}) {
+	left, leftArgs := e.left.SQL(env)
+	right, rightArgs := e.right.SQL(env)
+	return "(" + left + " " + e.op + " " + right + ")", append(leftArgs, rightArgs...)
+}
+
+type notExpr struct {
+	expr Expr
+}
+
+func (e notExpr) SQL(env Env) (string, []interface{}) {
+	sql, args := e.expr.SQL(env)
+	return "NOT " + sql, args
+}
+
+type boolExpr struct {
+	field field
+}
+
+func (e boolExpr) SQL(env Env) (string, []interface{}) {
+	return e.field.predicate(env)
+}
+
+type nullExpr struct {
+	field  field
+	negate bool
+}
+
+func (e nullExpr) SQL(env Env) (string, []interface{}) {
+	if e.negate {
+		return "(" + e.field.column + " IS NOT NULL)", nil
+	}
+	return "(" + e.field.column + " IS NULL)", nil
+}
+
+type compareExpr struct {
+	field field
+	op    string
+	value value
+}
+
+var sqlOperators = map[string]string{"=": "=", "!=": "<>", "<": "<", "<=": "<=", ">": ">", ">=": ">="}
+
+func (e compareExpr) SQL(env Env) (string, []interface{}) {
+	column := e.field.column
+
+	if e.op == "contains" {
+		return "(" + column + " ILIKE ?)", []interface{}{"%" + escapeLike(e.value.text) + "%"}
+	}
+
+	resolved := e.value.resolve(env)
+
+	// A whole day compared for equality covers every time on that day.
+	if day, ok := resolved.(time.Time); ok && e.value.isDay() && (e.op == "=" || e.op == "!=") {
+		next := day.AddDate(0, 0, 1)
+		if e.op == "=" {
+			return "(" + column + " >= ? AND " + column + " < ?)", []interface{}{day, next}
+		}
+		return "(" + column + " < ? OR " + column + " >= ?)", []interface{}{day, next}
+	}
+
+	return "(" + column + " " + sqlOperators[e.op] + " ?)", []interface{}{resolved}
+}
+
+type inExpr struct {
+	field  field
+	values []value
+	negate bool
+}
+
+func (e inExpr) SQL(env Env) (string, []interface{}) {
+	resolved := make([]interface{}, 0, len(e.values))
+	for _, v := range e.values {
+		resolved = append(resolved, v.resolve(env))
+	}
+	if e.negate {
+		return "(" + e.field.column + " NOT IN ?)", []interface{}{resolved}
+	}
+	return "(" + e.field.column + " IN ?)", []interface{}{resolved}
+}
+
+type valueKind int
+
+const (
+	valueString valueKind = iota
+	valueNumber
+	valueMe
+	valueNow
+	valueToday
+	// valueDate is a calendar day such as '2024-01-31'; valueTimestamp is an
+	// exact instant such as '2024-01-31T10:00:00Z'.
+	valueDate
+	valueTimestamp
+)
+
+type value struct {
+	kind   valueKind
+	text   string
+	number uint64
+	date   time.Time
+	offset time.Duration
+	days   int
+}
+
+func (v value) isDay() bool {
+	return (v.kind == valueToday || v.kind == valueDate) && v.offset == 0
+}
+
+func (v value) resolve(env Env) interface{} {
+	switch v.kind {
+	case valueNumber:
+		return v.number
+	case valueMe:
+		return env.UserID
+	case valueNow:
+		return env.Now.Add(v.offset).AddDate(0, 0, v.days)
+	case valueToday:
+		year, month, day := env.Now.UTC().Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Add(v.offset).AddDate(0, 0, v.days)
+	case valueDate, valueTimestamp:
+		return v.date
+	}
+	return v.text
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// parseOffset reads a relative offset such as 7d, 2w or 12h.
+func parseOffset(text string) (time.Duration, int, bool) {
+	if len(text) < 2 {
+		return 0, 0, false
+	}
+	n, err := strconv.Atoi(text[:len(text)-1])
+	if err != nil {
+		return 0, 0, false
+	}
+	switch text[len(text)-1] {
+	case 'h':
+		return time.Duration(n) * time.Hour, 0, true
+	case 'd':
+		return 0, n, true
+	case 'w':
+		return 0, 7 * n, true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,207 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testEnv = Env{
+	UserID: 7,
+	Now:    time.Date(2024, 3, 15, 13, 30, 0, 0, time.UTC),
+}
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		src  string
+		sql  string
+		args []interface{}
+	}{
+		{
+			src:  "status = 'completed'",
+			sql:  "(tasks.status = ?)",
+			args: []interface{}{"completed"},
+		},
+		{
+			src:  "assigned_to = me and priority in ('high', 'urgent')",
+			sql:  "((tasks.assigned_to = ?) AND (tasks.priority IN ?))",
+			args: []interface{}{uint(7), []interface{}{"high", "urgent"}},
+		},
+		{
+			src:  "created_by != 3 or not assigned_to = me",
+			sql:  "((tasks.created_by <> ?) OR NOT (tasks.assigned_to = ?))",
+			args: []interface{}{uint64(3), uint(7)},
+		},
+		{
+			src:  "status NOT IN ('completed')",
+			sql:  "(tasks.status NOT IN ?)",
+			args: []interface{}{[]interface{}{"completed"}},
+		},
+		{
+			src: "overdue",
+			sql: "(tasks.due_date < ? AND tasks.status <> ?)",
+			args: []interface{}{
+				testEnv.Now, "completed",
+			},
+		},
+		{
+			src: "due_date is null or due_date is not null",
+			sql: "((tasks.due_date IS NULL) OR (tasks.due_date IS NOT NULL))",
+		},
+		{
+			src:  "title contains '50%_off\\'",
+			sql:  "(tasks.title ILIKE ?)",
+			args: []interface{}{`%50\%\_off\\%`},
+		},
+		{
+			src:  "title = 'it''s'",
+			sql:  "(tasks.title = ?)",
+			args: []interface{}{"it's"},
+		},
+		{
+			src:  "(status = 'a' or status = 'b') and priority = 'low'",
+			sql:  "(((tasks.status = ?) OR (tasks.status = ?)) AND (tasks.priority = ?))",
+			args: []interface{}{"a", "b", "low"},
+		},
+		{
+			src:  "due_date < now+12h",
+			sql:  "(tasks.due_date < ?)",
+			args: []interface{}{testEnv.Now.Add(12 * time.Hour)},
+		},
+		{
+			src:  "updated_at >= now-2w",
+			sql:  "(tasks.updated_at >= ?)",
+			args: []interface{}{testEnv.Now.AddDate(0, 0, -14)},
+		},
+		{
+			src:  "due_date < today+7d",
+			sql:  "(tasks.due_date < ?)",
+			args: []interface{}{day(2024, 3, 22)},
+		},
+		{
+			src:  "due_date = today",
+			sql:  "(tasks.due_date >= ? AND tasks.due_date < ?)",
+			args: []interface{}{day(2024, 3, 15), day(2024, 3, 16)},
+		},
+		{
+			src:  "due_date != today-1d",
+			sql:  "(tasks.due_date < ? OR tasks.due_date >= ?)",
+			args: []interface{}{day(2024, 3, 14), day(2024, 3, 15)},
+		},
+		{
+			src:  "due_date = today+12h",
+			sql:  "(tasks.due_date = ?)",
+			args: []interface{}{day(2024, 3, 15).Add(12 * time.Hour)},
+		},
+		{
+			src:  "due_date = '2024-01-31'",
+			sql:  "(tasks.due_date >= ? AND tasks.due_date < ?)",
+			args: []interface{}{day(2024, 1, 31), day(2024, 2, 1)},
+		},
+		{
+			src:  "due_date = '2024-01-31T10:00:00Z'",
+			sql:  "(tasks.due_date = ?)",
+			args: []interface{}{time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			src:  "due_date != '2024-01-31T10:00:00Z'",
+			sql:  "(tasks.due_date <> ?)",
+			args: []interface{}{time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			src:  "created_at < '2024-01-31'",
+			sql:  "(tasks.created_at < ?)",
+			args: []interface{}{day(2024, 1, 31)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			expr, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			sql, args := expr.SQL(testEnv)
+			if sql != tt.sql {
+				t.Errorf("SQL = %s, want %s", sql, tt.sql)
+			}
+			if len(args) != 0 || len(tt.args) != 0 {
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("args = %#v, want %#v", args, tt.args)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+		msg string
+	}{
+		{"", 0, "expression is empty"},
+		{"   ", 0, "expression is empty"},
+		{"owner = me", 0, "unknown field owner"},
+		{"status", 6, "expected an operator after status"},
+		{"status = 'open", 9, "unterminated string"},
+		{"status = open", 9, "expected a quoted string"},
+		{"status ! 'open'", 7, "unknown operator !"},
+		{"status = 'a' extra", 13, `unexpected "extra"`},
+		{"(status = 'a'", 13, "expected )"},
+		{"assigned_to > 3", 12, "assigned_to only supports = and !="},
+		{"assigned_to = bob", 14, "expected a user ID or me"},
+		{"due_date contains 'x'", 9, "contains only works on text fields"},
+		{"due_date < '31/01/2024'", 11, "expected a date such as '2024-01-31'"},
+		{"due_date < tomorrow", 11, "expected a quoted date, now or today"},
+		{"due_date < now+7x", 15, "expected an offset such as 7d, 2w or 12h"},
+		{"due_date is 'x'", 12, "expected null"},
+		{"status in ('a' 'b')", 15, "expected , or )"},
+		{"status = 'a' # 1", 13, "unexpected character '#'"},
+		{strings.Repeat("not ", maxDepth) + "overdue", 4 * maxDepth, "expression is nested too deeply"},
+		{strings.Repeat("x", MaxLength+1), MaxLength, "expression is longer than 2000 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			var ferr *Error
+			if !errors.As(err, &ferr) {
+				t.Fatalf("Parse error = %v, want *Error", err)
+			}
+			if ferr.Pos != tt.pos || ferr.Msg != tt.msg {
+				t.Errorf("error = %q at %d, want %q at %d", ferr.Msg, ferr.Pos, tt.msg, tt.pos)
+			}
+		})
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		text     string
+		duration time.Duration
+		days     int
+		ok       bool
+	}{
+		{"12h", 12 * time.Hour, 0, true},
+		{"7d", 0, 7, true},
+		{"2w", 0, 14, true},
+		{"0d", 0, 0, true},
+		{"d", 0, 0, false},
+		{"7m", 0, 0, false},
+		{"7", 0, 0, false},
+		{"xd", 0, 0, false},
+	}
+	for _, tt := range tests {
+		duration, days, ok := parseOffset(tt.text)
+		if duration != tt.duration || days != tt.days || ok != tt.ok {
+			t.Errorf("parseOffset(%q) = %v, %d, %v, want %v, %d, %v", tt.text, duration, days, ok, tt.duration, tt.days, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// Error reports an invalid filter expression and where the problem is.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("filter: %s at position %d", e.Msg, e.Pos+1)
+}
+
+func errorAt(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+
+		case c == '\'' || c == '"':
+			// Strings are quoted with ' or "; the quote is escaped by doubling it.
+			start := i
+			var text strings.Builder
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, errorAt(start, "unterminated string")
+				}
+				if runes[i] == c {
+					if i+1 < len(runes) && runes[i+1] == c {
+						text.WriteRune(c)
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				text.WriteRune(runes[i])
+			}
+			tokens = append(tokens, token{tokenString, text.String(), start})
+
+		case strings.ContainsRune("=!<>", c):
+			start := i
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			}
+			op := string(runes[start:i])
+			if op == "!" {
+				return nil, errorAt(start, "unknown operator !")
+			}
+			tokens = append(tokens, token{tokenOperator, op, start})
+
+		case c == '+' || c == '-':
+			tokens = append(tokens, token{tokenOperator, string(c), i})
+			i++
+
+		case unicode.IsDigit(c):
+			// Numbers may carry a unit suffix, as in the 7d of now-7d.
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || unicode.IsLetter(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i]), start})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, strings.ToLower(string(runes[start:i])), start})
+
+		default:
+			return nil, errorAt(i, "unexpected character %q", c)
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "", len(runes)}), nil
+}
@@ -0,0 +1,259 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse checks an expression and returns it ready to be compiled.
+func Parse(src string) (Expr, error) {
+	if len(src) > MaxLength {
+		return nil, errorAt(MaxLength, "expression is longer than %d characters", MaxLength)
+	}
+	if strings.TrimSpace(src) == "" {
+		return nil, errorAt(0, "expression is empty")
+	}
+
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errorAt(tok.pos, "unexpected %q", tok.text)
+	}
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) acceptKeyword(word string) bool {
+	if tok := p.peek(); tok.kind == tokenIdent && tok.text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, errorAt(tok.pos, "expected %s", what)
+	}
+	return tok, nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, errorAt(p.peek().pos, "expression is nested too deeply")
+	}
+
+	if p.acceptKeyword("not") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	tok := p.next()
+
+	if tok.kind == tokenLParen {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	if tok.kind != tokenIdent {
+		return nil, errorAt(tok.pos, "expected a field name")
+	}
+	f, ok := fields[tok.text]
+	if !ok {
+		return nil, errorAt(tok.pos, "unknown field %s", tok.text)
+	}
+	if f.kind == kindBool {
+		return boolExpr{field: f}, nil
+	}
+
+	op := p.next()
+	switch {
+	case op.kind == tokenIdent && op.text == "is":
+		negate := p.acceptKeyword("not")
+		if !p.acceptKeyword("null") {
+			return nil, errorAt(p.peek().pos, "expected null")
+		}
+		return nullExpr{field: f, negate: negate}, nil
+
+	case op.kind == tokenIdent && (op.text == "in" || op.text == "not"):
+		negate := op.text == "not"
+		if negate && !p.acceptKeyword("in") {
+			return nil, errorAt(p.peek().pos, "expected in")
+		}
+		return p.parseList(f, negate)
+
+	case op.kind == tokenIdent && op.text == "contains":
+		if f.kind != kindString {
+			return nil, errorAt(op.pos, "contains only works on text fields")
+		}
+		v, err := p.parseValue(f)
+		if err != nil {
+			return nil, err
+		}
+		return compareExpr{field: f, op: "contains", value: v}, nil
+
+	case op.kind == tokenOperator && sqlOperators[op.text] != "":
+		if f.kind == kindUser && op.text != "=" && op.text != "!=" {
+			return nil, errorAt(op.pos, "%s only supports = and !=", tok.text)
+		}
+		v, err := p.parseValue(f)
+		if err != nil {
+			return nil, err
+		}
+		return compareExpr{field: f, op: op.text, value: v}, nil
+	}
+
+	return nil, errorAt(op.pos, "expected an operator after %s", tok.text)
+}
+
+func (p *parser) parseList(f field, negate bool) (Expr, error) {
+	if _, err := p.expect(tokenLParen, "("); err != nil {
+		return nil, err
+	}
+	var values []value
+	for {
+		v, err := p.parseValue(f)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		tok := p.next()
+		if tok.kind == tokenRParen {
+			break
+		}
+		if tok.kind != tokenComma {
+			return nil, errorAt(tok.pos, "expected , or )")
+		}
+	}
+	return inExpr{field: f, values: values, negate: negate}, nil
+}
+
+// parseValue reads a value and checks that it suits the field.
+func (p *parser) parseValue(f field) (value, error) {
+	tok := p.next()
+
+	switch f.kind {
+	case kindString:
+		if tok.kind != tokenString {
+			return value{}, errorAt(tok.pos, "expected a quoted string")
+		}
+		return value{kind: valueString, text: tok.text}, nil
+
+	case kindUser:
+		if tok.kind == tokenIdent && tok.text == "me" {
+			return value{kind: valueMe}, nil
+		}
+		if tok.kind == tokenNumber {
+			if n, err := strconv.ParseUint(tok.text, 10, 64); err == nil {
+				return value{kind: valueNumber, number: n}, nil
+			}
+		}
+		return value{}, errorAt(tok.pos, "expected a user ID or me")
+
+	case kindTime:
+		if tok.kind == tokenString {
+			if t, err := time.Parse(time.RFC3339, tok.text); err == nil {
+				return value{kind: valueTimestamp, date: t, text: tok.text}, nil
+			}
+			if t, err := time.Parse(time.DateOnly, tok.text); err == nil {
+				return value{kind: valueDate, date: t, text: tok.text}, nil
+			}
+			return value{}, errorAt(tok.pos, "expected a date such as '2024-01-31'")
+		}
+		if tok.kind != tokenIdent || (tok.text != "now" && tok.text != "today") {
+			return value{}, errorAt(tok.pos, "expected a quoted date, now or today")
+		}
+
+		v := value{kind: valueNow}
+		if tok.text == "today" {
+			v.kind = valueToday
+		}
+		if sign := p.peek(); sign.kind == tokenOperator && (sign.text == "+" || sign.text == "-") {
+			p.next()
+			amount := p.next()
+			offset, days, ok := parseOffset(amount.text)
+			if amount.kind != tokenNumber || !ok {
+				return value{}, errorAt(amount.pos, "expected an offset such as 7d, 2w or 12h")
+			}
+			if sign.text == "-" {
+				offset, days = -offset, -days
+			}
+			v.offset, v.days = offset, days
+		}
+		return v, nil
+	}
+
+	return value{}, errorAt(tok.pos, "unexpected value")
+}
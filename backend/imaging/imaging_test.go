@@ -0,0 +1,389 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage is w by h with a red top-left pixel, so orientation can be told.
+func testImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{0, 0, 255, 255})
+		}
+	}
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// jpegSegment builds a marker segment with its length field.
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// exifSegment is an APP1 segment holding a little-endian TIFF header with a
+// single orientation entry.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	ifd := make([]byte, 2+12+4)
+	binary.LittleEndian.PutUint16(ifd[0:], 1)
+	binary.LittleEndian.PutUint16(ifd[2:], 0x0112)
+	binary.LittleEndian.PutUint16(ifd[4:], 3)
+	binary.LittleEndian.PutUint32(ifd[6:], 1)
+	binary.LittleEndian.PutUint16(ifd[10:], orientation)
+	return jpegSegment(0xE1, append([]byte("Exif\x00\x00"), append(tiff, ifd...)...))
+}
+
+// withSegments inserts segments right after the JPEG's SOI marker.
+func withSegments(data []byte, segments ...[]byte) []byte {
+	out := append([]byte{}, data[:2]...)
+	for _, segment := range segments {
+		out = append(out, segment...)
+	}
+	return append(out, data[2:]...)
+}
+
+func pngChunk(kind string, payload []byte) []byte {
+	chunk := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], kind)
+	chunk = append(chunk, payload...)
+	crc := crc32.ChecksumIEEE(chunk[4:])
+	return binary.BigEndian.AppendUint32(chunk, crc)
+}
+
+// withChunks inserts chunks right after the PNG's IHDR chunk.
+func withChunks(data []byte, chunks ...[]byte) []byte {
+	ihdrEnd := len(pngSignature) + 12 + 13
+	out := append([]byte{}, data[:ihdrEnd]...)
+	for _, chunk := range chunks {
+		out = append(out, chunk...)
+	}
+	return append(out, data[ihdrEnd:]...)
+}
+
+func TestStripJPEG(t *testing.T) {
+	plain := encodeJPEG(t, testImage(8, 4))
+	tagged := withSegments(plain,
+		exifSegment(1),
+		jpegSegment(0xED, []byte("Photoshop 3.0\x00IPTC")),
+		jpegSegment(0xFE, []byte("shot at 51.5N 0.1W")),
+	)
+
+	stripped, changed, err := StripMetadata(tagged, "image/jpeg")
+	if err != nil || !changed {
+		t.Fatalf("StripMetadata = changed %t, %v", changed, err)
+	}
+	if !bytes.Equal(stripped, plain) {
+		t.Error("stripped JPEG differs from the original without metadata")
+	}
+	for _, leak := range []string{"Exif", "IPTC", "51.5N"} {
+		if bytes.Contains(stripped, []byte(leak)) {
+			t.Errorf("stripped JPEG still contains %q", leak)
+		}
+	}
+
+	same, changed, err := StripMetadata(plain, "image/jpeg")
+	if err != nil || changed || !bytes.Equal(same, plain) {
+		t.Errorf("JPEG without metadata: changed %t, %v", changed, err)
+	}
+}
+
+func TestStripJPEGAppliesOrientation(t *testing.T) {
+	tagged := withSegments(encodeJPEG(t, testImage(8, 4)), exifSegment(6))
+
+	stripped, changed, err := StripMetadata(tagged, "image/jpeg")
+	if err != nil || !changed {
+		t.Fatalf("StripMetadata = changed %t, %v", changed, err)
+	}
+	if jpegOrientation(stripped) != 0 || bytes.Contains(stripped, []byte("Exif")) {
+		t.Error("re-encoded JPEG still carries EXIF")
+	}
+	img, err := jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 4 || b.Dy() != 8 {
+		t.Errorf("rotated size = %dx%d, want 4x8", b.Dx(), b.Dy())
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	plain := encodePNG(t, testImage(4, 4))
+	tagged := withChunks(plain,
+		pngChunk("tEXt", []byte("Author\x00Jane")),
+		pngChunk("eXIf", []byte("MM\x00*")),
+		pngChunk("tIME", make([]byte, 7)),
+	)
+
+	stripped, changed, err := StripMetadata(tagged, "image/png")
+	if err != nil || !changed {
+		t.Fatalf("StripMetadata = changed %t, %v", changed, err)
+	}
+	if !bytes.Equal(stripped, plain) {
+		t.Error("stripped PNG differs from the original without metadata")
+	}
+	if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped PNG does not decode: %v", err)
+	}
+
+	same, changed, err := StripMetadata(plain, "image/png")
+	if err != nil || changed || !bytes.Equal(same, plain) {
+		t.Errorf("PNG without metadata: changed %t, %v", changed, err)
+	}
+}
+
+func TestStripMetadataMalformed(t *testing.T) {
+	jpg := encodeJPEG(t, testImage(4, 4))
+	pngData := encodePNG(t, testImage(4, 4))
+	gifData := encodeGIF(t, testImage(4, 4))
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+	}{
+		{"not a JPEG", []byte("GIF89a"), "image/jpeg"},
+		{"truncated JPEG segment", jpg[:5], "image/jpeg"},
+		{"JPEG segment past the end", withSegments([]byte{0xFF, 0xD8}, []byte{0xFF, 0xFE, 0xFF, 0xFF}), "image/jpeg"},
+		{"not a PNG", []byte("\xFF\xD8\xFF"), "image/png"},
+		{"truncated PNG chunk", pngData[:len(pngSignature)+10], "image/png"},
+		{"not a WebP", []byte("RIFF\x04\x00\x00\x00WAVE"), "image/webp"},
+		{"WebP chunk past the end", webpFile(webpChunk("VP8L", make([]byte, 4)))[:20], "image/webp"},
+		{"not a GIF", []byte("\x89PNG"), "image/gif"},
+		{"GIF without a trailer", gifData[:len(gifData)-1], "image/gif"},
+	}
+	for _, tt := range tests {
+		if _, _, err := StripMetadata(tt.data, tt.contentType); !errors.Is(err, errMalformed) {
+			t.Errorf("%s: error = %v, want errMalformed", tt.name, err)
+		}
+	}
+
+	// Other types are passed through untouched.
+	pdf := []byte("%PDF-1.7")
+	if out, changed, err := StripMetadata(pdf, "application/pdf"); err != nil || changed || !bytes.Equal(out, pdf) {
+		t.Errorf("PDF: changed %t, %v", changed, err)
+	}
+}
+
+// webpChunk builds a RIFF chunk, padded to an even length.
+func webpChunk(kind string, payload []byte) []byte {
+	chunk := append([]byte(kind), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// webpFile wraps chunks in a RIFF WEBP header.
+func webpFile(chunks ...[]byte) []byte {
+	data := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, chunk := range chunks {
+		data = append(data, chunk...)
+	}
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	return data
+}
+
+func TestStripWebP(t *testing.T) {
+	header := func(flags byte) []byte {
+		return webpChunk("VP8X", []byte{flags, 0, 0, 0, 3, 0, 0, 3, 0, 0})
+	}
+	pixels := webpChunk("VP8L", []byte("pixel data"))
+	icc := webpChunk("ICCP", []byte("profile"))
+
+	// ICC profile, EXIF and XMP flags.
+	tagged := webpFile(header(0x20|0x08|0x04), icc, pixels,
+		webpChunk("EXIF", []byte("MM\x00*GPS")),
+		webpChunk("XMP ", []byte("<x:xmpmeta/>")),
+	)
+	plain := webpFile(header(0x20), icc, pixels)
+
+	stripped, changed, err := StripMetadata(tagged, "image/webp")
+	if err != nil || !changed {
+		t.Fatalf("StripMetadata = changed %t, %v", changed, err)
+	}
+	if !bytes.Equal(stripped, plain) {
+		t.Errorf("stripped WebP = %q, want %q", stripped, plain)
+	}
+
+	same, changed, err := StripMetadata(plain, "image/webp")
+	if err != nil || changed || !bytes.Equal(same, plain) {
+		t.Errorf("WebP without metadata: changed %t, %v", changed, err)
+	}
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// gifExtension builds an extension block with a single sub-block.
+func gifExtension(label byte, payload []byte) []byte {
+	return append([]byte{0x21, label, byte(len(payload))}, append(payload, 0)...)
+}
+
+func TestStripGIF(t *testing.T) {
+	plain := encodeGIF(t, testImage(4, 4))
+	loop := append([]byte{0x21, 0xFF, 11}, "NETSCAPE2.0\x03\x01\x00\x00\x00"...)
+	xmp := append([]byte{0x21, 0xFF, 11}, "XMP DataXMP\x05<x:x>\x00"...)
+
+	// Extensions may come before the trailer; the looping one is kept.
+	trailer := len(plain) - 1
+	tagged := append(append([]byte{}, plain[:trailer]...), xmp...)
+	tagged = append(tagged, gifExtension(0xFE, []byte("taken at home"))...)
+	tagged = append(tagged, loop...)
+	tagged = append(tagged, 0x3B)
+	want := append(append(append([]byte{}, plain[:trailer]...), loop...), 0x3B)
+
+	stripped, changed, err := StripMetadata(tagged, "image/gif")
+	if err != nil || !changed {
+		t.Fatalf("StripMetadata = changed %t, %v", changed, err)
+	}
+	if !bytes.Equal(stripped, want) {
+		t.Error("stripped GIF differs from the original without metadata")
+	}
+	if _, err := gif.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped GIF does not decode: %v", err)
+	}
+
+	same, changed, err := StripMetadata(plain, "image/gif")
+	if err != nil || changed || !bytes.Equal(same, plain) {
+		t.Errorf("GIF without metadata: changed %t, %v", changed, err)
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	plain := encodeJPEG(t, testImage(2, 2))
+	for orientation := uint16(1); orientation <= 8; orientation++ {
+		if got := jpegOrientation(withSegments(plain, exifSegment(orientation))); got != int(orientation) {
+			t.Errorf("orientation %d read as %d", orientation, got)
+		}
+	}
+	if got := jpegOrientation(withSegments(plain, exifSegment(9))); got != 0 {
+		t.Errorf("out of range orientation read as %d", got)
+	}
+	if got := jpegOrientation(plain); got != 0 {
+		t.Errorf("JPEG without EXIF has orientation %d", got)
+	}
+	if got := jpegOrientation([]byte("not a jpeg")); got != 0 {
+		t.Errorf("non-JPEG has orientation %d", got)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := testImage(3, 2)
+	red := color.RGBAModel.Convert(color.RGBA{255, 0, 0, 255})
+
+	// Where the top-left pixel ends up, and the resulting size.
+	tests := []struct {
+		orientation int
+		x, y, w, h  int
+	}{
+		{1, 0, 0, 3, 2},
+		{2, 2, 0, 3, 2},
+		{3, 2, 1, 3, 2},
+		{4, 0, 1, 3, 2},
+		{5, 0, 0, 2, 3},
+		{6, 1, 0, 2, 3},
+		{7, 1, 2, 2, 3},
+		{8, 0, 2, 2, 3},
+	}
+	for _, tt := range tests {
+		out := orient(img, tt.orientation)
+		if b := out.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orientation %d: size %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if got := color.RGBAModel.Convert(out.At(tt.x, tt.y)); got != red {
+			t.Errorf("orientation %d: pixel (%d,%d) = %v, want red", tt.orientation, tt.x, tt.y, got)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		w, h        int
+	}{
+		{"wide JPEG", encodeJPEG(t, testImage(640, 320)), "image/jpeg", 320, 160},
+		{"tall PNG", encodePNG(t, testImage(100, 400)), "image/png", 80, 320},
+		{"small image keeps its size", encodePNG(t, testImage(50, 20)), "image/png", 50, 20},
+		{"thin image keeps a pixel", encodePNG(t, testImage(2000, 1)), "image/png", 320, 1},
+		{"rotated JPEG", withSegments(encodeJPEG(t, testImage(640, 320)), exifSegment(6)), "image/jpeg", 160, 320},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumb, contentType, err := Thumbnail(tt.data, 320)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if contentType != tt.contentType {
+				t.Errorf("content type = %s, want %s", contentType, tt.contentType)
+			}
+			img, _, err := image.Decode(bytes.NewReader(thumb))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+				t.Errorf("size = %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.w, tt.h)
+			}
+			if bytes.Contains(thumb, []byte("Exif")) {
+				t.Error("thumbnail carries EXIF")
+			}
+		})
+	}
+}
+
+func TestThumbnailRejectsHugeImages(t *testing.T) {
+	// Only the header is read, so a PNG claiming huge dimensions is enough.
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], 20000)
+	binary.BigEndian.PutUint32(ihdr[4:], 20000)
+	ihdr[8], ihdr[9] = 8, 2
+	data := append(append([]byte{}, pngSignature...), pngChunk("IHDR", ihdr)...)
+
+	if _, _, err := Thumbnail(data, 320); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("error = %v, want ErrTooLarge", err)
+	}
+	if _, _, err := Thumbnail([]byte("not an image"), 320); err == nil {
+		t.Error("non-image accepted")
+	}
+}
@@ -0,0 +1,334 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/jpeg"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks carry camera, location and editing details.
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// webpMetadataChunks are the RIFF chunks holding EXIF and XMP, and
+// webpMetadataFlags their bits in the VP8X header.
+var (
+	webpMetadataChunks = map[string]bool{"EXIF": true, "XMP ": true}
+	webpMetadataFlags  = byte(0x08 | 0x04)
+)
+
+// gifXMPApplication identifies the application extension holding XMP.
+const gifXMPApplication = "XMP DataXMP"
+
+var errMalformed = errors.New("imaging: malformed image")
+
+// StripMetadata removes EXIF and other metadata from JPEG, PNG, WebP and GIF
+// images and reports whether anything changed. Pixel data is copied as is, except for
+// JPEGs whose EXIF orientation has to be applied before it is dropped.
+func StripMetadata(data []byte, contentType string) ([]byte, bool, error) {
+	switch contentType {
+	case "image/jpeg":
+		if orientation := jpegOrientation(data); orientation > 1 {
+			return reencodeJPEG(data, orientation)
+		}
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	case "image/gif":
+		return stripGIF(data)
+	}
+	return data, false, nil
+}
+
+func reencodeJPEG(data []byte, orientation int) ([]byte, bool, error) {
+	if err := checkSize(data); err != nil {
+		return nil, false, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, orient(img, orientation), &jpeg.Options{Quality: 92}); err != nil {
+		return nil, false, err
+	}
+	return out.Bytes(), true, nil
+}
+
+// stripJPEG drops APP1 (EXIF, XMP), APP13 (IPTC) and comment segments.
+func stripJPEG(data []byte) ([]byte, bool, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, false, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	changed := false
+	i := 2
+	for i < len(data) {
+		if data[i] != 0xFF || i+1 >= len(data) {
+			return nil, false, errMalformed
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			// Fill byte before a marker.
+			i++
+			continue
+		case marker == 0xD9, marker == 0x01, marker >= 0xD0 && marker <= 0xD7:
+			out.Write(data[i : i+2])
+			i += 2
+			continue
+		}
+
+		if i+4 > len(data) {
+			return nil, false, errMalformed
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) {
+			return nil, false, errMalformed
+		}
+		if marker == 0xDA {
+			// The image data follows the start of scan; keep the rest as is.
+			out.Write(data[i:])
+			break
+		}
+		if marker == 0xE1 || marker == 0xED || marker == 0xFE {
+			changed = true
+		} else {
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	if !changed {
+		return data, false, nil
+	}
+	return out.Bytes(), true, nil
+}
+
+func stripPNG(data []byte) ([]byte, bool, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, false, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+	changed := false
+	for i := len(pngSignature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, false, errMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, false, errMalformed
+		}
+		if pngMetadataChunks[string(data[i+4:i+8])] {
+			changed = true
+		} else {
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	if !changed {
+		return data, false, nil
+	}
+	return out.Bytes(), true, nil
+}
+
+// stripWebP drops the EXIF and XMP chunks and clears their flags in the
+// extended header.
+func stripWebP(data []byte) ([]byte, bool, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, false, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+	changed := false
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, false, errMalformed
+		}
+		length := int(binary.LittleEndian.Uint32(data[i+4:]))
+		// Chunks are padded to an even length.
+		end := i + 8 + length + length%2
+		if length < 0 || end > len(data) {
+			return nil, false, errMalformed
+		}
+		kind := string(data[i : i+4])
+		switch {
+		case webpMetadataChunks[kind]:
+			changed = true
+		case kind == "VP8X" && length > 0 && data[i+8]&webpMetadataFlags != 0:
+			chunk := append([]byte{}, data[i:end]...)
+			chunk[8] &^= webpMetadataFlags
+			out.Write(chunk)
+			changed = true
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	if !changed {
+		return data, false, nil
+	}
+	stripped := out.Bytes()
+	binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+	return stripped, true, nil
+}
+
+// stripGIF drops comment extensions and the application extension holding
+// XMP. Other extensions, such as the one making animations loop, are kept.
+func stripGIF(data []byte) ([]byte, bool, error) {
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return nil, false, errMalformed
+	}
+
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+	if i > len(data) {
+		return nil, false, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:i])
+	changed := false
+	for {
+		if i >= len(data) {
+			return nil, false, errMalformed
+		}
+		start := i
+		switch data[i] {
+		case 0x3B:
+			// The trailer ends the image; anything after it is dropped.
+			out.WriteByte(0x3B)
+			if !changed && i+1 == len(data) {
+				return data, false, nil
+			}
+			return out.Bytes(), true, nil
+		case 0x21:
+			if i+2 > len(data) {
+				return nil, false, errMalformed
+			}
+			label := data[i+1]
+			end, ok := gifSubBlocksEnd(data, i+2)
+			if !ok {
+				return nil, false, errMalformed
+			}
+			xmp := label == 0xFF && i+3+len(gifXMPApplication) <= len(data) && data[i+2] == byte(len(gifXMPApplication)) &&
+				string(data[i+3:i+3+len(gifXMPApplication)]) == gifXMPApplication
+			if label == 0xFE || xmp {
+				changed = true
+			} else {
+				out.Write(data[start:end])
+			}
+			i = end
+		case 0x2C:
+			if i+10 > len(data) {
+				return nil, false, errMalformed
+			}
+			i += 10
+			if flags := data[i-1]; flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			// The LZW minimum code size precedes the image data.
+			end, ok := gifSubBlocksEnd(data, i+1)
+			if !ok {
+				return nil, false, errMalformed
+			}
+			out.Write(data[start:end])
+			i = end
+		default:
+			return nil, false, errMalformed
+		}
+	}
+}
+
+// gifSubBlocksEnd follows the size-prefixed sub-blocks starting at i and
+// returns the offset past their terminator.
+func gifSubBlocksEnd(data []byte, i int) (int, bool) {
+	for i < len(data) {
+		size := int(data[i])
+		i++
+		if size == 0 {
+			return i, true
+		}
+		i += size
+	}
+	return 0, false
+}
+
+// jpegOrientation reads the EXIF orientation (1 to 8) of a JPEG, or 0 when
+// it has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 0
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 0
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) {
+			return 0
+		}
+		if segment := data[i+4 : end]; marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i = end
+	}
+	return 0
+}
+
+// exifOrientation finds the orientation tag in the first IFD of a TIFF
+// structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			return 0
+		}
+	}
+	return 0
+}
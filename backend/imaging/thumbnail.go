@@ -0,0 +1,109 @@
+// Package imaging makes thumbnails of uploaded images and strips their
+// metadata.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels bounds the images that are decoded, so a small file that
+// expands to a huge bitmap cannot exhaust memory.
+const MaxPixels = 40_000_000
+
+var ErrTooLarge = errors.New("imaging: image is too large")
+
+func checkSize(data []byte) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// Thumbnail scales an image to fit within maxSide pixels, applying its EXIF
+// orientation. The result is a PNG for formats that may be transparent and a
+// JPEG otherwise, and carries no metadata.
+func Thumbnail(data []byte, maxSide int) ([]byte, string, error) {
+	if err := checkSize(data); err != nil {
+		return nil, "", err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxSide || height > maxSide {
+		if width >= height {
+			width, height = maxSide, max(1, height*maxSide/width)
+		} else {
+			width, height = max(1, width*maxSide/height), maxSide
+		}
+	}
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Over, nil)
+
+	var thumb image.Image = scaled
+	if format == "jpeg" {
+		thumb = orient(scaled, jpegOrientation(data))
+	}
+
+	var out bytes.Buffer
+	if format == "png" || format == "gif" {
+		err = png.Encode(&out, thumb)
+		return out.Bytes(), "image/png", err
+	}
+	err = jpeg.Encode(&out, thumb, &jpeg.Options{Quality: 85})
+	return out.Bytes(), "image/jpeg", err
+}
+
+// orient turns an image as described by an EXIF orientation value.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// Orientations 5 to 8 swap width and height.
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			out.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Send delivers a plain text email through the configured SMTP server. When
+// SMTP_HOST is not set the message is only logged, which keeps local setups
+// working without a mail server.
+func Send(to, subject, body string) error {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		log.Printf("[Mail] SMTP not configured, would send to %s: %s\n%s", to, subject, body)
+		return nil
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + headerValue(from),
+		"To: " + headerValue(to),
+		"Subject: " + headerValue(subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(host+":"+port, auth, from, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("send mail to %s: %w", to, err)
+	}
+	return nil
+}
+
+// headerValue flattens line breaks and drops other control characters, so
+// a value cannot end its header and start another or the body.
+func headerValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\r' || r == '\n':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// SendAsync sends in the background and logs failures, for callers that must
+// not block a request on mail delivery.
+func SendAsync(to, subject, body string) {
+	go func() {
+		if err := Send(to, subject, body); err != nil {
+			log.Printf("[Mail] %v", err)
+		}
+	}()
+}
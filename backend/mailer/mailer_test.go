@@ -0,0 +1,21 @@
+package mailer
+
+import "testing"
+
+func TestHeaderValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Reset your password", "Reset your password"},
+		{"Hi\r\nBcc: victim@example.com", "Hi  Bcc: victim@example.com"},
+		{"a\nb\rc", "a b c"},
+		{"tab\there\x00\x1b[0m", "tabhere[0m"},
+		{"Ünïcödé ✓", "Ünïcödé ✓"},
+	}
+	for _, tt := range tests {
+		if got := headerValue(tt.value); got != tt.want {
+			t.Errorf("headerValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -6,11 +6,17 @@ import (
 	"net/http"
 	"os"
 	"time"
+	// Embedded zone data lets quiet hours use any time zone on hosts without it.
+	_ "time/tzdata"
 
 	"github.com/joho/godotenv"
 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/controller"
 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/scanner"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/storage"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/websocket"
 )
 
 func main() {
@@ -19,6 +25,14 @@ func main() {
 	}
 
 	database.ConnectDB()
+	storage.Connect()
+	scanner.Connect()
+
+	// Background jobs
+	go runPeriodically(time.Hour, controller.PurgeDeletedAccounts)
+	go runPeriodically(time.Hour, controller.PurgeTrashedTasks)
+	go runPeriodically(15*time.Minute, controller.NotifyDueSoonTasks)
+	go controller.RunAttachmentWorker(time.Minute)
 
 	mux := http.NewServeMux()
 
@@ -28,7 +42,7 @@ func main() {
 	// Apply middleware chain
 	// Apply middleware chain in correct order
 
-	handler := corsMiddleware(LoggingMiddleware(RecoveryMiddleware(mux)))
+	handler := corsMiddleware(middleware.RequestIDMiddleware(LoggingMiddleware(RecoveryMiddleware(mux))))
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -43,30 +57,128 @@ func setupRoutes(mux *http.ServeMux) {
 	// Auth routes
 	mux.HandleFunc("POST /api/auth/register", controller.RegisterUser)
 	mux.HandleFunc("POST /api/auth/login", controller.LoginUser)
+	mux.HandleFunc("GET /api/auth/oidc/login", controller.OIDCLogin)
+	mux.HandleFunc("GET /api/auth/oidc/callback", controller.OIDCCallback)
+
+	// Admin routes
+	mux.HandleFunc("GET /api/admin/users", middleware.AuthMiddleware(middleware.RequireAdmin(controller.AdminListUsers)))
+	mux.HandleFunc("GET /api/admin/users/{id}", middleware.AuthMiddleware(middleware.RequireAdmin(controller.AdminGetUser)))
+	mux.HandleFunc("GET /api/admin/users/{id}/tasks", middleware.AuthMiddleware(middleware.RequireAdmin(controller.AdminGetUserTasks)))
+	mux.HandleFunc("POST /api/admin/users/{id}/disable", middleware.AuthMiddleware(middleware.RequireAdmin(controller.AdminDisableUser)))
+	mux.HandleFunc("POST /api/admin/users/{id}/enable", middleware.AuthMiddleware(middleware.RequireAdmin(controller.AdminEnableUser)))
+	mux.HandleFunc("POST /api/admin/users/{id}/force-password-reset", middleware.AuthMiddleware(middleware.RequireAdmin(controller.AdminForcePasswordReset)))
+	mux.HandleFunc("POST /api/admin/users/{id}/impersonate", middleware.AuthMiddleware(middleware.RequireAdmin(controller.AdminImpersonateUser)))
+	mux.HandleFunc("GET /api/admin/audit-log", middleware.AuthMiddleware(middleware.RequireAdmin(controller.AdminGetAuditLog)))
+	mux.HandleFunc("GET /api/admin/login-attempts", middleware.AuthMiddleware(middleware.RequireAdmin(controller.AdminGetLoginAttempts)))
+
+	// Current user routes
+	mux.HandleFunc("GET /api/users/me", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeProfileRead, controller.GetCurrentUser)))
+	mux.HandleFunc("PATCH /api/users/me", middleware.AuthMiddleware(middleware.RequireSession(controller.UpdateCurrentUser)))
+	mux.HandleFunc("POST /api/users/me/verify-email", middleware.AuthMiddleware(middleware.RequireSession(controller.RequestEmailVerification)))
+	mux.HandleFunc("POST /api/users/me/password", middleware.AuthMiddleware(middleware.RequireSession(controller.ChangePassword)))
+	mux.HandleFunc("DELETE /api/users/me", middleware.AuthMiddleware(middleware.RequireSession(controller.DeleteCurrentUser)))
+	mux.HandleFunc("POST /api/users/me/cancel-deletion", middleware.AuthMiddleware(middleware.RequireSession(controller.CancelAccountDeletion)))
+	mux.HandleFunc("GET /api/users/me/export", middleware.AuthMiddleware(middleware.RequireSession(controller.ExportCurrentUser)))
+	mux.HandleFunc("GET /api/users/me/notification-settings", middleware.AuthMiddleware(middleware.RequireSession(controller.GetNotificationSettings)))
+	mux.HandleFunc("PUT /api/users/me/notification-settings", middleware.AuthMiddleware(middleware.RequireSession(controller.UpdateNotificationSettings)))
+	mux.HandleFunc("POST /api/auth/verify-email", controller.VerifyEmail)
+	mux.HandleFunc("POST /api/auth/reset-password", controller.ResetPassword)
+
+	// Workspace and invitation routes
+	mux.HandleFunc("GET /api/workspaces", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeWorkspacesRead, controller.GetWorkspaces)))
+	mux.HandleFunc("POST /api/workspaces", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeWorkspacesWrite, controller.CreateWorkspace)))
+	mux.HandleFunc("GET /api/workspaces/{id}/members", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeWorkspacesRead, controller.GetWorkspaceMembers)))
+	mux.HandleFunc("GET /api/workspaces/{id}/invitations", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeWorkspacesRead, controller.GetInvitations)))
+	mux.HandleFunc("POST /api/workspaces/{id}/invitations", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeWorkspacesWrite, controller.CreateInvitation)))
+	mux.HandleFunc("DELETE /api/workspaces/{id}/invitations/{invitationId}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeWorkspacesWrite, controller.RevokeInvitation)))
+	mux.HandleFunc("GET /api/workspaces/{id}/labels", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeLabelsRead, controller.GetLabels)))
+	mux.HandleFunc("POST /api/workspaces/{id}/labels", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeLabelsWrite, controller.CreateLabel)))
+	mux.HandleFunc("GET /api/invitations/{token}", controller.GetInvitation)
+	mux.HandleFunc("POST /api/invitations/{token}/accept", middleware.AuthMiddleware(middleware.RequireSession(controller.AcceptInvitation)))
+
+	// Label routes
+	mux.HandleFunc("PATCH /api/labels/{id}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeLabelsWrite, controller.UpdateLabel)))
+	mux.HandleFunc("POST /api/labels/{id}/merge", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeLabelsWrite, controller.MergeLabel)))
+	mux.HandleFunc("DELETE /api/labels/{id}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeLabelsWrite, controller.DeleteLabel)))
+
+	// Saved view routes
+	mux.HandleFunc("GET /api/views", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeViewsRead, controller.GetViews)))
+	mux.HandleFunc("POST /api/views", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeViewsWrite, controller.CreateView)))
+	mux.HandleFunc("PATCH /api/views/{id}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeViewsWrite, controller.UpdateView)))
+	mux.HandleFunc("DELETE /api/views/{id}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeViewsWrite, controller.DeleteView)))
+	mux.HandleFunc("GET /api/views/{id}/tasks", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeViewsRead, middleware.RequireScope(model.ScopeTasksRead, controller.GetViewTasks))))
+
+	// Notification routes
+	mux.HandleFunc("GET /api/notifications", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeNotificationsRead, controller.GetNotifications)))
+	mux.HandleFunc("POST /api/notifications/read-all", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeNotificationsWrite, controller.MarkAllNotificationsRead)))
+	mux.HandleFunc("POST /api/notifications/{id}/read", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeNotificationsWrite, controller.MarkNotificationRead)))
+
+	// Session routes
+	mux.HandleFunc("GET /api/sessions", middleware.AuthMiddleware(middleware.RequireSession(controller.GetSessions)))
+	mux.HandleFunc("DELETE /api/sessions/{id}", middleware.AuthMiddleware(middleware.RequireSession(controller.RevokeSession)))
+
+	// Personal access token routes
+	mux.HandleFunc("GET /api/tokens", middleware.AuthMiddleware(middleware.RequireSession(controller.GetAPITokens)))
+	mux.HandleFunc("POST /api/tokens", middleware.AuthMiddleware(middleware.RequireSession(controller.CreateAPIToken)))
+	mux.HandleFunc("DELETE /api/tokens/{id}", middleware.AuthMiddleware(middleware.RequireSession(controller.DeleteAPIToken)))
 
 	// Task routes with auth middleware
-	mux.HandleFunc("GET /api/tasks/", middleware.AuthMiddleware(controller.GetAllTasks))
-	mux.HandleFunc("POST /api/tasks", middleware.AuthMiddleware(controller.CreateTask))
+	mux.HandleFunc("GET /api/tasks/", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.GetAllTasks)))
+	mux.HandleFunc("POST /api/tasks", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.CreateTask)))
 	mux.HandleFunc("OPTIONS /api/tasks/", handleCORSOptions)
-	mux.HandleFunc("GET /api/tasks/{id}/", middleware.AuthMiddleware(controller.GetTaskByID))
-	mux.HandleFunc("PUT /api/tasks/{id}/", middleware.AuthMiddleware(controller.UpdateTask))
-	mux.HandleFunc("DELETE /api/tasks/{id}/", middleware.AuthMiddleware(controller.DeleteTask))
+	mux.HandleFunc("GET /api/tasks/search", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.SearchTasks)))
+	mux.HandleFunc("GET /api/tasks/trash", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.GetTrashedTasks)))
+	mux.HandleFunc("GET /api/tasks/{id}/history", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.GetTaskHistory)))
+	mux.HandleFunc("GET /api/tasks/{id}/revisions", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.GetTaskRevisions)))
+	mux.HandleFunc("POST /api/tasks/{id}/revert", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.RevertTask)))
+	mux.HandleFunc("POST /api/tasks/{id}/restore", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.RestoreTask)))
+	mux.HandleFunc("GET /api/tasks/{id}/attachments", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.GetAttachments)))
+	mux.HandleFunc("POST /api/tasks/{id}/attachments", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.UploadAttachment)))
+	mux.HandleFunc("GET /api/tasks/{id}/attachments/{attachmentId}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.DownloadAttachment)))
+	mux.HandleFunc("GET /api/tasks/{id}/attachments/{attachmentId}/thumbnail", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.DownloadAttachmentThumbnail)))
+	mux.HandleFunc("DELETE /api/tasks/{id}/attachments/{attachmentId}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.DeleteAttachment)))
+	mux.HandleFunc("GET /api/tasks/{id}/dependencies", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.GetTaskDependencies)))
+	mux.HandleFunc("POST /api/tasks/{id}/dependencies", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.AddTaskDependency)))
+	mux.HandleFunc("DELETE /api/tasks/{id}/dependencies/{otherId}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.RemoveTaskDependency)))
+	mux.HandleFunc("GET /api/tasks/{id}/watchers", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.GetTaskWatchers)))
+	mux.HandleFunc("POST /api/tasks/{id}/watchers", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.WatchTask)))
+	mux.HandleFunc("DELETE /api/tasks/{id}/watchers/{userId}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.UnwatchTask)))
+	mux.HandleFunc("GET /api/tasks/{id}/comments", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.GetComments)))
+	mux.HandleFunc("POST /api/tasks/{id}/comments", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.CreateComment)))
+	mux.HandleFunc("PATCH /api/tasks/{id}/comments/{commentId}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.UpdateComment)))
+	mux.HandleFunc("DELETE /api/tasks/{id}/comments/{commentId}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.DeleteComment)))
+	mux.HandleFunc("GET /api/tasks/{id}/comments/{commentId}/edits", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.GetCommentEdits)))
+	mux.HandleFunc("POST /api/tasks/{id}/labels", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.AddTaskLabel)))
+	mux.HandleFunc("DELETE /api/tasks/{id}/labels/{labelId}", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.RemoveTaskLabel)))
+	mux.HandleFunc("GET /api/tasks/{id}/", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, controller.GetTaskByID)))
+	mux.HandleFunc("PUT /api/tasks/{id}/", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.UpdateTask)))
+	mux.HandleFunc("PATCH /api/tasks/{id}/", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.PatchTask)))
+	mux.HandleFunc("DELETE /api/tasks/{id}/", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksWrite, controller.DeleteTask)))
 
 	// AI Suggestions route
-	mux.HandleFunc("POST /api/ai/suggest", middleware.AuthMiddleware(controller.GetAISuggestions))
+	mux.HandleFunc("POST /api/ai/suggest", middleware.AuthMiddleware(middleware.RequireScope(model.ScopeAISuggest, controller.GetAISuggestions)))
+
+	// WebSocket route
+	mux.HandleFunc("GET /ws/{id}", middleware.TokenFromQuery(middleware.AuthMiddleware(middleware.RequireScope(model.ScopeTasksRead, websocket.HandleWebSocket))))
+}
 
-	// // WebSocket route
-	// mux.HandleFunc("GET /ws/{id}/", func(w http.ResponseWriter, r *http.Request) {
-	// 	// WebSocket implementation
-	// })
+// runPeriodically runs job immediately and then on every tick.
+func runPeriodically(interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job()
+		<-ticker.C
+	}
 }
 
 // Middleware implementations
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "*")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-ID")
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -86,7 +198,8 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		next.ServeHTTP(w, r)
-		log.Printf("%s %s %v", r.Method, r.URL.Path, time.Since(start))
+		requestID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+		log.Printf("%s %s %v request_id=%s", r.Method, r.URL.Path, time.Since(start), requestID)
 	})
 }
 
@@ -0,0 +1,64 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:   "emphasis",
+			source: "**bold** and _italic_",
+			want:   []string{"<strong>bold</strong>", "<em>italic</em>"},
+		},
+		{
+			name:   "lists and strikethrough",
+			source: "- done\n- ~~todo~~",
+			want:   []string{"<li>done</li>", "<del>todo</del>"},
+		},
+		{
+			name:    "raw HTML is dropped",
+			source:  "hi <script>alert(1)</script> <img src=x onerror=alert(1)>",
+			notWant: []string{"<script", "<img", "onerror"},
+		},
+		{
+			name:    "javascript links are removed",
+			source:  "[click](javascript:alert(1))",
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:   "external links open safely",
+			source: "[docs](https://example.com)",
+			want:   []string{`href="https://example.com"`, `rel="nofollow noopener"`, `target="_blank"`},
+		},
+		{
+			name:   "code is escaped",
+			source: "`<b>`",
+			want:   []string{"<code>&lt;b&gt;</code>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := Render(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(html, s) {
+					t.Errorf("output %q is missing %q", html, s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(html, s) {
+					t.Errorf("output %q contains %q", html, s)
+				}
+			}
+		})
+	}
+}
@@ -3,18 +3,37 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey contextKey = "user_id"
+	// ScopesKey is only set for requests authenticated with a personal access token.
+	ScopesKey contextKey = "scopes"
+	// TokenIDKey is only set for requests authenticated with a personal access token.
+	TokenIDKey contextKey = "token_id"
+	// SessionIDKey is only set for requests authenticated with a login JWT.
+	SessionIDKey contextKey = "session_id"
+	// ImpersonatorIDKey holds the admin's user ID on impersonated sessions.
+	ImpersonatorIDKey contextKey = "impersonator_id"
+	UserRoleKey       contextKey = "user_role"
+)
+
+// sessionTouchInterval limits how often LastSeenAt is written for busy
+// sessions, and LastUsedAt for busy tokens.
+const sessionTouchInterval = time.Minute
 
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
@@ -26,11 +45,15 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func GenerateToken(userID uint) (string, error) {
+// SessionTTL is how long a login session, and the JWT issued for it, stays valid.
+const SessionTTL = time.Hour * 72
+
+func GenerateToken(userID uint, sessionID string, expiresAt time.Time) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
 	claims["user_id"] = userID
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix()
+	claims["sid"] = sessionID
+	claims["exp"] = expiresAt.Unix()
 
 	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 	if err != nil {
@@ -50,6 +73,20 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
+		if strings.HasPrefix(tokenString, APITokenPrefix) {
+			apiToken, err := lookupAPIToken(tokenString)
+			if err != nil {
+				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, apiToken.UserID)
+			ctx = context.WithValue(ctx, ScopesKey, apiToken.ScopeList())
+			ctx = context.WithValue(ctx, TokenIDKey, apiToken.ID)
+			serveActiveUser(w, r.WithContext(ctx), apiToken.UserID, next)
+			return
+		}
+
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -68,8 +105,154 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		userID := uint(claims["user_id"].(float64))
+		userIDClaim, ok := claims["user_id"].(float64)
+		sessionID, hasSession := claims["sid"].(string)
+		if !ok || !hasSession {
+			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+			return
+		}
+		userID := uint(userIDClaim)
+
+		session, err := touchSession(sessionID, userID)
+		if err != nil {
+			http.Error(w, "Session revoked or expired", http.StatusUnauthorized)
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+		if session.ImpersonatorID != nil {
+			ctx = context.WithValue(ctx, ImpersonatorIDKey, *session.ImpersonatorID)
+		}
+		serveActiveUser(w, r.WithContext(ctx), userID, next)
+	}
+}
+
+// serveActiveUser rejects disabled accounts, even when their token is still
+// valid, and records the user's role for RequireAdmin.
+func serveActiveUser(w http.ResponseWriter, r *http.Request, userID uint, next http.HandlerFunc) {
+	var user model.User
+	if err := database.DB.Select("id", "role", "disabled_at").First(&user, userID).Error; err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if user.DisabledAt != nil {
+		http.Error(w, "Account disabled", http.StatusForbidden)
+		return
+	}
+
+	if impersonatorID, ok := r.Context().Value(ImpersonatorIDKey).(uint); ok && r.Method != http.MethodGet {
+		recordImpersonatedRequest(impersonatorID, userID, r)
+	}
+
+	ctx := context.WithValue(r.Context(), UserRoleKey, user.Role)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireAdmin only lets administrators through. Personal access tokens and
+// impersonated sessions never count as admin.
+func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(UserRoleKey).(string)
+		_, isAPIToken := r.Context().Value(ScopesKey).([]string)
+		_, isImpersonated := r.Context().Value(ImpersonatorIDKey).(uint)
+		if role != model.RoleAdmin || isAPIToken || isImpersonated {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func recordImpersonatedRequest(impersonatorID, userID uint, r *http.Request) {
+	entry := model.AdminAuditLog{
+		ActorID:      impersonatorID,
+		Action:       model.AuditActionImpersonatedRequest,
+		TargetUserID: &userID,
+		Details:      r.Method + " " + r.URL.Path,
+		IP:           ClientIP(r),
+	}
+	if err := database.DB.Create(&entry).Error; err != nil {
+		log.Printf("Could not record impersonated request: %v", err)
+	}
+}
+
+// TokenFromQuery accepts the bearer token as a token query parameter, for
+// clients such as browser WebSockets that cannot set request headers.
+func TokenFromQuery(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next(w, r)
+	}
+}
+
+// RequireScope rejects personal access tokens that were not granted scope.
+// Requests authenticated with a login JWT carry every scope.
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scopes, isAPIToken := r.Context().Value(ScopesKey).([]string)
+		if isAPIToken && !slices.Contains(scopes, scope) {
+			http.Error(w, "Token is missing required scope: "+scope, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// RequireSession rejects personal access tokens and impersonated sessions
+// outright, for account routes that only the user themselves may use. An
+// impersonating admin must not mint tokens, change credentials, export the
+// account or delete it.
+func RequireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, isAPIToken := r.Context().Value(ScopesKey).([]string); isAPIToken {
+			http.Error(w, "API tokens cannot access this endpoint", http.StatusForbidden)
+			return
+		}
+		if _, isImpersonated := r.Context().Value(ImpersonatorIDKey).(uint); isImpersonated {
+			http.Error(w, "Impersonated sessions cannot access this endpoint", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func lookupAPIToken(tokenString string) (*model.APIToken, error) {
+	var apiToken model.APIToken
+	if err := database.DB.Where("token_hash = ?", HashToken(tokenString)).First(&apiToken).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if apiToken.ExpiresAt != nil && apiToken.ExpiresAt.Before(now) {
+		return nil, fmt.Errorf("token expired")
+	}
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) > sessionTouchInterval {
+		if err := database.DB.Model(&apiToken).Update("last_used_at", now).Error; err != nil {
+			log.Printf("Could not record token usage: %v", err)
+		}
+	}
+	return &apiToken, nil
+}
+
+// touchSession checks that the session is still active and records activity.
+func touchSession(sessionID string, userID uint) (*model.Session, error) {
+	var session model.Session
+	now := time.Now()
+	err := database.DB.
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, now).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := database.DB.Model(&session).Update("last_seen_at", now).Error; err != nil {
+			log.Printf("Could not record session activity: %v", err)
+		}
 	}
+	return &session, nil
 }
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+// caller describes how a request was authenticated, as AuthMiddleware would
+// record it in the context.
+type caller struct {
+	role         string
+	scopes       []string
+	impersonator uint
+}
+
+func (c caller) request() *http.Request {
+	ctx := context.WithValue(context.Background(), UserIDKey, uint(1))
+	ctx = context.WithValue(ctx, UserRoleKey, c.role)
+	if c.scopes != nil {
+		ctx = context.WithValue(ctx, ScopesKey, c.scopes)
+	} else {
+		ctx = context.WithValue(ctx, SessionIDKey, "session")
+	}
+	if c.impersonator != 0 {
+		ctx = context.WithValue(ctx, ImpersonatorIDKey, c.impersonator)
+	}
+	return httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+}
+
+func serve(guard func(http.HandlerFunc) http.HandlerFunc, c caller) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	guard(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})(rec, c.request())
+	return rec
+}
+
+func TestRequireSession(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller caller
+		status int
+	}{
+		{"login session", caller{role: model.RoleUser}, http.StatusNoContent},
+		{"admin session", caller{role: model.RoleAdmin}, http.StatusNoContent},
+		{"API token", caller{role: model.RoleUser, scopes: model.ValidScopes}, http.StatusForbidden},
+		{"API token without scopes", caller{role: model.RoleUser, scopes: []string{}}, http.StatusForbidden},
+		{"impersonated session", caller{role: model.RoleUser, impersonator: 9}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if rec := serve(RequireSession, tt.caller); rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	requireTasksRead := func(next http.HandlerFunc) http.HandlerFunc {
+		return RequireScope(model.ScopeTasksRead, next)
+	}
+	tests := []struct {
+		name   string
+		caller caller
+		status int
+	}{
+		{"login session", caller{role: model.RoleUser}, http.StatusNoContent},
+		{"impersonated session", caller{role: model.RoleUser, impersonator: 9}, http.StatusNoContent},
+		{"token with the scope", caller{scopes: []string{model.ScopeTasksWrite, model.ScopeTasksRead}}, http.StatusNoContent},
+		{"token without the scope", caller{scopes: []string{model.ScopeTasksWrite}}, http.StatusForbidden},
+		{"token without scopes", caller{scopes: []string{}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if rec := serve(requireTasksRead, tt.caller); rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller caller
+		status int
+	}{
+		{"admin session", caller{role: model.RoleAdmin}, http.StatusNoContent},
+		{"user session", caller{role: model.RoleUser}, http.StatusForbidden},
+		{"admin's API token", caller{role: model.RoleAdmin, scopes: model.ValidScopes}, http.StatusForbidden},
+		{"admin impersonated by another admin", caller{role: model.RoleAdmin, impersonator: 9}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if rec := serve(RequireAdmin, tt.caller); rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+}
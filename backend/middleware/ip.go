@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/config"
+)
+
+// ClientIP returns the address of the caller. X-Forwarded-For is only trusted
+// when TRUST_PROXY_HEADERS=true, since clients can set it freely otherwise.
+// Even then only the entries appended by the TRUSTED_PROXY_HOPS proxies in
+// front of the server count: the client's address is the last one added by
+// the outermost of them, and anything to its left came from the client.
+func ClientIP(r *http.Request) string {
+	if os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+		if ip := forwardedFor(r.Header.Values("X-Forwarded-For"), config.Int("TRUSTED_PROXY_HOPS", 1)); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor picks the entry hops from the right of the X-Forwarded-For
+// headers, or "" if there is no valid one.
+func forwardedFor(headers []string, hops int) string {
+	var entries []string
+	for _, header := range headers {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if len(entries) == 0 || hops < 1 {
+		return ""
+	}
+
+	// With fewer entries than proxies, every entry was added by a proxy.
+	entry := entries[0]
+	if len(entries) >= hops {
+		entry = entries[len(entries)-hops]
+	}
+	if net.ParseIP(entry) == nil {
+		return ""
+	}
+	return entry
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestForwardedFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		hops    int
+		want    string
+	}{
+		{"no header", nil, 1, ""},
+		{"single proxy", []string{"203.0.113.7"}, 1, "203.0.113.7"},
+		{"spoofed entry ignored", []string{"1.2.3.4, 203.0.113.7"}, 1, "203.0.113.7"},
+		{"two proxies", []string{"1.2.3.4, 203.0.113.7, 10.0.0.2"}, 2, "203.0.113.7"},
+		{"split across headers", []string{"1.2.3.4", "203.0.113.7"}, 1, "203.0.113.7"},
+		{"fewer entries than proxies", []string{"203.0.113.7"}, 2, "203.0.113.7"},
+		{"IPv6", []string{"2001:db8::1"}, 1, "2001:db8::1"},
+		{"not an address", []string{"1.2.3.4, unknown"}, 1, ""},
+		{"no trusted proxies", []string{"203.0.113.7"}, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := forwardedFor(tt.headers, tt.hops); got != tt.want {
+			t.Errorf("%s: forwardedFor(%q, %d) = %q, want %q", tt.name, tt.headers, tt.hops, got, tt.want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:4321"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.7")
+
+	if got := ClientIP(r); got != "10.0.0.1" {
+		t.Errorf("untrusted proxy headers: ClientIP = %q, want the remote address", got)
+	}
+
+	t.Setenv("TRUST_PROXY_HEADERS", "true")
+	if got := ClientIP(r); got != "203.0.113.7" {
+		t.Errorf("trusted proxy headers: ClientIP = %q, want the entry added by the proxy", got)
+	}
+	t.Setenv("TRUSTED_PROXY_HOPS", "2")
+	if got := ClientIP(r); got != "1.2.3.4" {
+		t.Errorf("two trusted proxies: ClientIP = %q, want 1.2.3.4", got)
+	}
+}
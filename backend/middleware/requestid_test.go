@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"", false},
+		{"abc-123", true},
+		{"req_1.2-A", true},
+		{strings.Repeat("a", maxRequestIDLength), true},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+		{"abc def", false},
+		{"abc\r\nSet-Cookie: x=1", false},
+		{"\x1b[31mred", false},
+		{"ünïcode", false},
+	}
+	for _, tt := range tests {
+		if got := validRequestID(tt.id); got != tt.want {
+			t.Errorf("validRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = r.Context().Value(RequestIDKey).(string)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		reuse  bool
+	}{
+		{"reuses a valid ID", "client-id.42", true},
+		{"generates when missing", "", false},
+		{"replaces control characters", "bad\x1b[0mid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Request-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			got := w.Header().Get("X-Request-ID")
+			if got != seen {
+				t.Errorf("response ID %q differs from context ID %q", got, seen)
+			}
+			if (got == tt.header) != tt.reuse {
+				t.Errorf("X-Request-ID = %q for header %q, reuse = %v", got, tt.header, tt.reuse)
+			}
+			if !validRequestID(got) {
+				t.Errorf("X-Request-ID %q is not valid", got)
+			}
+		})
+	}
+}
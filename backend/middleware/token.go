@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// APITokenPrefix marks bearer tokens that are personal access tokens rather than JWTs.
+const APITokenPrefix = "zkt_"
+
+// GenerateAPIToken returns a new random personal access token in plaintext.
+// Only its hash should ever be persisted.
+func GenerateAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return APITokenPrefix + hex.EncodeToString(buf), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
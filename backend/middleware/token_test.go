@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/database/dbtest"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+func TestGenerateAPIToken(t *testing.T) {
+	first, err := GenerateAPIToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenerateAPIToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(first, APITokenPrefix) {
+		t.Errorf("token %q does not start with %q", first, APITokenPrefix)
+	}
+	if len(first) != len(APITokenPrefix)+64 {
+		t.Errorf("token is %d characters long, want %d", len(first), len(APITokenPrefix)+64)
+	}
+	if first == second {
+		t.Error("two generated tokens are equal")
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	token := APITokenPrefix + "secret"
+	hash := HashToken(token)
+	if hash != HashToken(token) {
+		t.Error("HashToken is not deterministic")
+	}
+	if len(hash) != 64 || strings.Contains(hash, "secret") {
+		t.Errorf("HashToken(%q) = %q, want a hex SHA-256 digest", token, hash)
+	}
+	if hash == HashToken(token+"x") {
+		t.Error("different tokens share a hash")
+	}
+}
+
+// authenticate runs AuthMiddleware for a request bearing token and returns the
+// response along with the scopes the handler saw.
+func authenticate(token string) (*httptest.ResponseRecorder, []string) {
+	var scopes []string
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		scopes, _ = r.Context().Value(ScopesKey).([]string)
+		w.WriteHeader(http.StatusNoContent)
+	})(rec, req)
+	return rec, scopes
+}
+
+func TestAuthMiddlewareAPIToken(t *testing.T) {
+	dbtest.Open(t)
+
+	user := model.User{Name: "Ada", Email: "ada@example.com"}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	newToken := func(scopes string, expiresAt *time.Time) string {
+		t.Helper()
+		plaintext, err := GenerateAPIToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		token := model.APIToken{
+			UserID:    user.ID,
+			Name:      "test",
+			Prefix:    plaintext[:len(APITokenPrefix)+6],
+			TokenHash: HashToken(plaintext),
+			Scopes:    scopes,
+			ExpiresAt: expiresAt,
+		}
+		if err := database.DB.Create(&token).Error; err != nil {
+			t.Fatal(err)
+		}
+		return plaintext
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		token := newToken(model.ScopeTasksRead+","+model.ScopeTasksWrite, nil)
+		rec, scopes := authenticate(token)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+		}
+		if !slices.Equal(scopes, []string{model.ScopeTasksRead, model.ScopeTasksWrite}) {
+			t.Errorf("scopes = %v", scopes)
+		}
+
+		var stored model.APIToken
+		database.DB.Where("token_hash = ?", HashToken(token)).First(&stored)
+		if stored.LastUsedAt == nil {
+			t.Error("LastUsedAt was not recorded")
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		expired := time.Now().Add(-time.Minute)
+		if rec, _ := authenticate(newToken(model.ScopeTasksRead, &expired)); rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		unknown, _ := GenerateAPIToken()
+		if rec, _ := authenticate(unknown); rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("disabled account", func(t *testing.T) {
+		token := newToken(model.ScopeTasksRead, nil)
+		database.DB.Model(&user).Update("disabled_at", time.Now())
+		defer database.DB.Model(&user).Update("disabled_at", nil)
+		if rec, _ := authenticate(token); rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
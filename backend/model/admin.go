@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+const (
+	AuditActionDisableUser         = "disable_user"
+	AuditActionEnableUser          = "enable_user"
+	AuditActionForcePasswordReset  = "force_password_reset"
+	AuditActionImpersonate         = "impersonate"
+	AuditActionImpersonatedRequest = "impersonated_request"
+)
+
+// AdminAuditLog records every privileged action taken by an administrator,
+// including each write made while impersonating a user.
+type AdminAuditLog struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ActorID      uint      `gorm:"index" json:"actor_id"`
+	Action       string    `json:"action"`
+	TargetUserID *uint     `gorm:"index" json:"target_user_id"`
+	Details      string    `json:"details"`
+	IP           string    `json:"ip"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+// PasswordReset lets a user choose a new password through an emailed link.
+type PasswordReset struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index"`
+	TokenHash string    `gorm:"uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type AdminActionInput struct {
+	Reason string `json:"reason"`
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
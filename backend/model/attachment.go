@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Scan states of an attachment. Only clean attachments can be downloaded.
+const (
+	AttachmentPending  = "pending"
+	AttachmentScanning = "scanning"
+	AttachmentClean    = "clean"
+	AttachmentInfected = "infected"
+	// AttachmentScanFailed means the scanner kept failing on the file.
+	AttachmentScanFailed = "failed"
+)
+
+// Attachment is a file uploaded to a task. The contents live in file
+// storage under StorageKey.
+type Attachment struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TaskID      uint      `gorm:"index" json:"task_id"`
+	UploadedBy  uint      `json:"uploaded_by"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `gorm:"uniqueIndex" json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	ScanStatus    string     `gorm:"default:pending;index" json:"scan_status"`
+	ScanResult    string     `json:"scan_result,omitempty"`
+	ScanAttempts  int        `json:"-"`
+	ScanStartedAt *time.Time `json:"-"`
+	ScannedAt     *time.Time `json:"scanned_at"`
+
+	ThumbnailKey         string `json:"-"`
+	ThumbnailContentType string `json:"thumbnail_content_type,omitempty"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxCommentLength bounds the Markdown source of a comment.
+const MaxCommentLength = 10000
+
+// Comment is a Markdown message on a task. Replies point at their parent and
+// deleted comments keep their place in the thread without their content.
+type Comment struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	TaskID    uint           `gorm:"index" json:"task_id"`
+	ParentID  *uint          `gorm:"index" json:"parent_id"`
+	AuthorID  uint           `gorm:"index" json:"author_id"`
+	Body      string         `json:"body"`
+	BodyHTML  string         `json:"body_html"`
+	EditedAt  *time.Time     `json:"edited_at"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
+
+// CommentEdit keeps the body a comment had before an edit.
+type CommentEdit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CommentID uint      `gorm:"index" json:"comment_id"`
+	Body      string    `json:"body"`
+	EditedBy  uint      `json:"edited_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CommentInput struct {
+	Body     string `json:"body" validate:"required"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type CommentUpdateInput struct {
+	Body string `json:"body" validate:"required"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// TaskDependency says that BlockerID blocks BlockedID: the blocked task
+// cannot be completed while the blocker is open.
+type TaskDependency struct {
+	BlockerID uint      `gorm:"primaryKey" json:"blocker_id"`
+	BlockedID uint      `gorm:"primaryKey;index" json:"blocked_id"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskDependencyInput relates a task to another one, in either direction.
+// Exactly one of the fields must be set.
+type TaskDependencyInput struct {
+	BlockedBy uint `json:"blocked_by"`
+	Blocks    uint `json:"blocks"`
+}
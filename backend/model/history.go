@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+const (
+	TaskActionCreated  = "created"
+	TaskActionUpdated  = "updated"
+	TaskActionDeleted  = "deleted"
+	TaskActionRestored = "restored"
+	TaskActionReverted = "reverted"
+)
+
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// TaskHistory is an append-only record of a change to a task. Rows are
+// protected against updates and deletes by a database trigger.
+type TaskHistory struct {
+	ID      uint `gorm:"primaryKey" json:"id"`
+	TaskID  uint `gorm:"index" json:"task_id"`
+	ActorID uint `json:"actor_id"`
+	// ImpersonatorID is the admin acting as ActorID, if any.
+	ImpersonatorID *uint                  `json:"impersonator_id,omitempty"`
+	Action         string                 `json:"action"`
+	Changes        map[string]FieldChange `json:"changes" gorm:"type:jsonb;serializer:json"`
+	RequestID      string                 `json:"request_id"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// TaskRevision is a snapshot of a task's editable fields as of Version.
+type TaskRevision struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	TaskID      uint       `gorm:"uniqueIndex:idx_task_revision" json:"task_id"`
+	Version     uint       `gorm:"uniqueIndex:idx_task_revision" json:"version"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority"`
+	DueDate     *time.Time `json:"due_date"`
+	AssignedTo  uint       `json:"assigned_to"`
+	ActorID     uint       `json:"actor_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type RevertTaskInput struct {
+	Revision uint `json:"revision"`
+}
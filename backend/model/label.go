@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// DefaultLabelColor is used when a label is created without a color.
+const DefaultLabelColor = "#808080"
+
+// Label categorises tasks. Labels belong to a workspace and can be put on any
+// task by its members.
+type Label struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint      `gorm:"uniqueIndex:idx_label_workspace_name" json:"workspace_id"`
+	Name        string    `gorm:"uniqueIndex:idx_label_workspace_name" json:"name"`
+	Color       string    `json:"color"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type LabelInput struct {
+	Name  string `json:"name" validate:"required"`
+	Color string `json:"color"`
+}
+
+type LabelUpdateInput struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+type LabelMergeInput struct {
+	Into uint `json:"into"`
+}
+
+type TaskLabelInput struct {
+	LabelID uint `json:"label_id"`
+}
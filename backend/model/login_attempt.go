@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+const (
+	LoginOutcomeSuccess         = "success"
+	LoginOutcomeInvalidPassword = "invalid_password"
+	LoginOutcomeUnknownUser     = "unknown_user"
+	LoginOutcomeAccountLocked   = "account_locked"
+	LoginOutcomeLockedOut       = "locked_out"
+	LoginOutcomeIPBlocked       = "ip_blocked"
+	// The password was right but the account may not sign in.
+	LoginOutcomeAccountDisabled       = "account_disabled"
+	LoginOutcomePasswordResetRequired = "password_reset_required"
+)
+
+// LoginAttempt is the audit record of a single password login attempt.
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    *uint     `gorm:"index" json:"user_id"`
+	Email     string    `gorm:"index" json:"email"`
+	IP        string    `gorm:"index:idx_login_attempts_ip_created_at" json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Outcome   string    `json:"outcome"`
+	CreatedAt time.Time `gorm:"index:idx_login_attempts_ip_created_at" json:"created_at"`
+}
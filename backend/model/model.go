@@ -2,6 +2,8 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type User struct {
@@ -10,40 +12,64 @@ type User struct {
 	Email    string `json:"email" gorm:"unique"`
 	Password string `json:"-"`
 	Tasks    []Task `json:"tasks,omitempty" gorm:"foreignKey:AssignedTo"`
+
+	EmailVerified bool            `json:"email_verified"`
+	PendingEmail  string          `json:"pending_email,omitempty"`
+	Preferences   UserPreferences `json:"preferences" gorm:"type:jsonb;serializer:json"`
+
+	FailedLoginAttempts int        `json:"-"`
+	LastFailedLoginAt   *time.Time `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+
+	Role                  string     `json:"role" gorm:"default:user"`
+	DisabledAt            *time.Time `json:"-"`
+	PasswordResetRequired bool       `json:"-"`
+
+	DeletionScheduledAt  *time.Time `json:"-"`
+	DeletionTaskStrategy string     `json:"-"`
+	DeletionReassignTo   *uint      `json:"-"`
 }
 
 type Task struct {
-	ID          uint       `gorm:"primaryKey" json:"id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Status      string     `json:"status"`
-	Priority    string     `json:"priority"`
-	DueDate     time.Time  `json:"due_date"`
-	AssignedTo  uint       `json:"assigned_to"`
-	CreatedBy   uint       `json:"created_by"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Status      string         `json:"status" gorm:"index"`
+	Priority    string         `json:"priority" gorm:"index"`
+	DueDate     *time.Time     `json:"due_date" gorm:"index"`
+	AssignedTo  uint           `json:"assigned_to" gorm:"index"`
+	CreatedBy   uint           `json:"created_by" gorm:"index"`
+	Version     uint           `json:"version" gorm:"not null;default:1"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"index"`
+	UpdatedAt   time.Time      `json:"updated_at" gorm:"index"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	Labels      []Label        `json:"labels,omitempty" gorm:"many2many:task_labels;"`
+
+	// StatusBeforeBlocked is the status to return to once the blockers that
+	// moved the task to "blocked" are done.
+	StatusBeforeBlocked string `json:"-"`
 }
 
 type LoginInput struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Email       string `json:"email" validate:"required,email"`
+	Password    string `json:"password" validate:"required"`
+	InviteToken string `json:"invite_token"`
 }
 
 type RegisterInput struct {
-	Name     string `json:"name" validate:"required"`
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Name        string `json:"name" validate:"required"`
+	Email       string `json:"email" validate:"required,email"`
+	Password    string `json:"password" validate:"required,min=6"`
+	InviteToken string `json:"invite_token"`
 }
 
 type TaskInput struct {
-	Title       string    `json:"title" validate:"required"`
-	Description string    `json:"description"`
-	Status      string    `json:"status"`
-	Priority    string    `json:"priority"`
-	DueDate     time.Time `json:"due_date"`
-	AssignedTo  uint      `json:"assigned_to"`
+	Title       string     `json:"title" validate:"required"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority"`
+	DueDate     *time.Time `json:"due_date"`
+	AssignedTo  uint       `json:"assigned_to"`
 }
 
 type AISuggestionInput struct {
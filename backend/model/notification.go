@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+const (
+	NotificationAssigned      = "assigned"
+	NotificationStatusChanged = "status_changed"
+	NotificationDueSoon       = "due_soon"
+	NotificationMention       = "mention"
+	NotificationComment       = "comment"
+)
+
+// NotificationTypes lists the event types users can route in their settings.
+var NotificationTypes = []string{
+	NotificationAssigned,
+	NotificationStatusChanged,
+	NotificationDueSoon,
+	NotificationMention,
+	NotificationComment,
+}
+
+// Notification tells a user about something that happened to a task.
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index:idx_notification_user_read" json:"user_id"`
+	Type      string     `json:"type"`
+	TaskID    *uint      `gorm:"index" json:"task_id"`
+	CommentID *uint      `json:"comment_id,omitempty"`
+	ActorID   uint       `json:"actor_id"`
+	Message   string     `json:"message"`
+	ReadAt    *time.Time `gorm:"index:idx_notification_user_read" json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// DueSoonReminder records that a user was reminded of a task's due date, so
+// the reminder goes out once whichever channel delivers it.
+type DueSoonReminder struct {
+	TaskID    uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"primaryKey;index"`
+	DueDate   time.Time `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+const (
+	TaskAccessMention = "mention"
+)
+
+// TaskAccess gives a user read access to a task they neither created nor
+// are assigned to.
+type TaskAccess struct {
+	TaskID    uint      `gorm:"primaryKey" json:"task_id"`
+	UserID    uint      `gorm:"primaryKey;index" json:"user_id"`
+	Reason    string    `json:"reason"`
+	GrantedBy uint      `json:"granted_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// OIDCIdentity links an identity at an external OpenID Connect provider to a local user.
+type OIDCIdentity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Issuer    string    `gorm:"uniqueIndex:idx_oidc_issuer_subject" json:"issuer"`
+	Subject   string    `gorm:"uniqueIndex:idx_oidc_issuer_subject" json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OIDCAuthRequest holds the state of an authorization-code flow between the
+// redirect to the provider and the callback.
+type OIDCAuthRequest struct {
+	State        string    `gorm:"primaryKey"`
+	Nonce        string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
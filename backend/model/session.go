@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Session is a server-side record of a login. Every JWT carries the ID of the
+// session it was issued for, so revoking the session invalidates the token.
+type Session struct {
+	ID         string     `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+	// ImpersonatorID is set when an admin is acting as this user.
+	ImpersonatorID *uint `json:"impersonator_id,omitempty"`
+}
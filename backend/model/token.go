@@ -0,0 +1,53 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	ScopeTasksRead          = "tasks:read"
+	ScopeTasksWrite         = "tasks:write"
+	ScopeAISuggest          = "ai:suggest"
+	ScopeProfileRead        = "profile:read"
+	ScopeWorkspacesRead     = "workspaces:read"
+	ScopeWorkspacesWrite    = "workspaces:write"
+	ScopeLabelsRead         = "labels:read"
+	ScopeLabelsWrite        = "labels:write"
+	ScopeViewsRead          = "views:read"
+	ScopeViewsWrite         = "views:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+)
+
+// ValidScopes lists the scopes that can be granted to a personal access token.
+var ValidScopes = []string{
+	ScopeTasksRead, ScopeTasksWrite, ScopeAISuggest, ScopeProfileRead,
+	ScopeWorkspacesRead, ScopeWorkspacesWrite, ScopeLabelsRead, ScopeLabelsWrite,
+	ScopeViewsRead, ScopeViewsWrite, ScopeNotificationsRead, ScopeNotificationsWrite,
+}
+
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `gorm:"uniqueIndex" json:"-"`
+	Scopes     string     `json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t APIToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+type APITokenInput struct {
+	Name          string   `json:"name" validate:"required"`
+	Scopes        []string `json:"scopes" validate:"required"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
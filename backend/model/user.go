@@ -0,0 +1,128 @@
+package model
+
+import "time"
+
+// UserPreferences backs the settings page and is stored as JSON on the user row.
+type UserPreferences struct {
+	DarkMode           bool   `json:"dark_mode"`
+	InAppNotifications bool   `json:"in_app_notifications"`
+	EmailNotifications bool   `json:"email_notifications"`
+	Language           string `json:"language"`
+
+	Notifications NotificationSettings `json:"notifications"`
+}
+
+// Notification channels a user can pick per event type.
+const (
+	ChannelInApp   = "in_app"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelNone    = "none"
+)
+
+// NotificationSettings chooses how each type of notification reaches the
+// user. Event types without an entry are delivered in-app.
+type NotificationSettings struct {
+	Channels   map[string]string `json:"channels"`
+	WebhookURL string            `json:"webhook_url"`
+	QuietHours *QuietHours       `json:"quiet_hours"`
+}
+
+// QuietHours is a daily window, in the user's time zone, during which
+// notifications are only put in the inbox. Start and End are "HH:MM" and the
+// window may wrap past midnight.
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"time_zone"`
+}
+
+// EmailVerification confirms ownership of an address before it replaces the
+// user's current email.
+type EmailVerification struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index"`
+	Email     string    `gorm:"not null"`
+	TokenHash string    `gorm:"uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type PreferencesInput struct {
+	DarkMode           *bool   `json:"dark_mode"`
+	InAppNotifications *bool   `json:"in_app_notifications"`
+	EmailNotifications *bool   `json:"email_notifications"`
+	Language           *string `json:"language"`
+}
+
+type NotificationSettingsInput struct {
+	Channels   map[string]string `json:"channels"`
+	WebhookURL *string           `json:"webhook_url"`
+	QuietHours *QuietHours       `json:"quiet_hours"`
+	// ClearQuietHours turns quiet hours off.
+	ClearQuietHours bool `json:"clear_quiet_hours"`
+}
+
+type ProfileUpdateInput struct {
+	Name        *string           `json:"name"`
+	Email       *string           `json:"email"`
+	Preferences *PreferencesInput `json:"preferences"`
+}
+
+type PasswordChangeInput struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// DefaultPreferences mirrors the defaults of the frontend settings page.
+func DefaultPreferences() UserPreferences {
+	return UserPreferences{
+		InAppNotifications: true,
+		EmailNotifications: true,
+		Language:           "en",
+	}
+}
+
+const (
+	// TaskStrategyReassign hands a deleted user's tasks to another user.
+	TaskStrategyReassign = "reassign"
+	// TaskStrategyAnonymize keeps the tasks but clears the deleted user from them.
+	TaskStrategyAnonymize = "anonymize"
+)
+
+type AccountDeletionInput struct {
+	Password     string `json:"password"`
+	TaskStrategy string `json:"task_strategy" validate:"required"`
+	ReassignTo   uint   `json:"reassign_to"`
+	// ConfirmationToken stands in for the password of accounts that sign in
+	// with single sign-on. It is emailed on the first request.
+	ConfirmationToken string `json:"confirmation_token"`
+}
+
+// AccountDeletionConfirmation lets an account without a password prove it
+// controls its email before deleting itself.
+type AccountDeletionConfirmation struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index"`
+	TokenHash string    `gorm:"uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// AISuggestionRecord keeps the history of AI suggestions requested by a user.
+type AISuggestionRecord struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	UserID          uint      `gorm:"index" json:"-"`
+	TaskDescription string    `json:"task_description"`
+	Title           string    `json:"title"`
+	Subtasks        []string  `json:"subtasks" gorm:"type:jsonb;serializer:json"`
+	Priority        string    `json:"priority"`
+	TimeEstimate    float64   `json:"time_estimate"`
+	CreatedAt       time.Time `json:"created_at"`
+}
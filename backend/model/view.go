@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// SavedView is a named task filter. Views with a WorkspaceID are shared with
+// every member of that workspace; the others are private to their owner.
+type SavedView struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `json:"name"`
+	Filter      string    `json:"filter"`
+	Sort        string    `json:"sort"`
+	OwnerID     uint      `gorm:"index" json:"owner_id"`
+	WorkspaceID *uint     `gorm:"index" json:"workspace_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SavedViewInput struct {
+	Name        string `json:"name" validate:"required"`
+	Filter      string `json:"filter" validate:"required"`
+	Sort        string `json:"sort"`
+	WorkspaceID *uint  `json:"workspace_id"`
+}
+
+type SavedViewUpdateInput struct {
+	Name   *string `json:"name"`
+	Filter *string `json:"filter"`
+	Sort   *string `json:"sort"`
+}
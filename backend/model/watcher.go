@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// TaskWatcher follows a task: watchers hear about its updates and comments
+// and can read it.
+type TaskWatcher struct {
+	TaskID    uint      `gorm:"primaryKey" json:"task_id"`
+	UserID    uint      `gorm:"primaryKey;index" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WatchTaskInput struct {
+	// UserID adds someone else as a watcher. It defaults to the caller.
+	UserID uint `json:"user_id"`
+}
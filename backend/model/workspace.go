@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+)
+
+// WorkspaceRoleRank orders roles from least to most privileged.
+var WorkspaceRoleRank = map[string]int{
+	WorkspaceRoleMember: 1,
+	WorkspaceRoleAdmin:  2,
+	WorkspaceRoleOwner:  3,
+}
+
+type Workspace struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   uint      `gorm:"index" json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID uint      `gorm:"primaryKey" json:"workspace_id"`
+	UserID      uint      `gorm:"primaryKey;index" json:"user_id"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Invitation grants a role in a workspace to whoever redeems its token. When
+// Email is set only the account with that address can accept it.
+type Invitation struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint       `gorm:"index" json:"workspace_id"`
+	Email       string     `json:"email,omitempty"`
+	Role        string     `json:"role"`
+	TokenHash   string     `gorm:"uniqueIndex" json:"-"`
+	InvitedBy   uint       `json:"invited_by"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy  *uint      `json:"accepted_by,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type WorkspaceInput struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type InvitationInput struct {
+	Email          string `json:"email"`
+	Role           string `json:"role"`
+	ExpiresInHours int    `json:"expires_in_hours"`
+}
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const clamavChunkSize = 64 << 10
+
+// ClamAV streams files to a clamd daemon with the INSTREAM command.
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAV takes the daemon's address as tcp://host:port or
+// unix:///path/to/clamd.sock.
+func NewClamAV(address string) *ClamAV {
+	network, addr := "tcp", address
+	if scheme, rest, ok := strings.Cut(address, "://"); ok {
+		network, addr = scheme, rest
+	}
+	return &ClamAV{network: network, address: addr, timeout: 2 * time.Minute}
+}
+
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return Result{}, fmt.Errorf("clamav: connect: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Result{}, fmt.Errorf("clamav: send command: %w", err)
+	}
+
+	chunk := make([]byte, clamavChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, err := r.Read(chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, werr := conn.Write(size); werr != nil {
+				return Result{}, fmt.Errorf("clamav: send data: %w", werr)
+			}
+			if _, werr := conn.Write(chunk[:n]); werr != nil {
+				return Result{}, fmt.Errorf("clamav: send data: %w", werr)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("clamav: read file: %w", err)
+		}
+	}
+	// A zero-length chunk ends the stream.
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return Result{}, fmt.Errorf("clamav: send data: %w", err)
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return Result{}, fmt.Errorf("clamav: read reply: %w", err)
+	}
+	return parseClamAVReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamAVReply reads replies such as "stream: OK" and
+// "stream: Eicar-Test-Signature FOUND".
+func parseClamAVReply(reply string) (Result, error) {
+	verdict := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case verdict == "OK":
+		return Result{Clean: true}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return Result{Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	default:
+		return Result{}, fmt.Errorf("clamav: %s", reply)
+	}
+}
@@ -0,0 +1,141 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseClamAVReply(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  Result
+		err   bool
+	}{
+		{"stream: OK", Result{Clean: true}, false},
+		{"stream: Eicar-Test-Signature FOUND", Result{Signature: "Eicar-Test-Signature"}, false},
+		{"stream: Win.Test.EICAR_HDB-1 FOUND", Result{Signature: "Win.Test.EICAR_HDB-1"}, false},
+		{"INSTREAM size limit exceeded. ERROR", Result{}, true},
+		{"stream: lstat() failed. ERROR", Result{}, true},
+		{"", Result{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseClamAVReply(tt.reply)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseClamAVReply(%q) = %+v, %v", tt.reply, got, err)
+		}
+	}
+}
+
+func TestNewClamAV(t *testing.T) {
+	tests := []struct {
+		address, network, addr string
+	}{
+		{"tcp://clamd:3310", "tcp", "clamd:3310"},
+		{"unix:///run/clamd.sock", "unix", "/run/clamd.sock"},
+		{"localhost:3310", "tcp", "localhost:3310"},
+	}
+	for _, tt := range tests {
+		c := NewClamAV(tt.address)
+		if c.network != tt.network || c.address != tt.addr {
+			t.Errorf("NewClamAV(%q) = %s %s, want %s %s", tt.address, c.network, c.address, tt.network, tt.addr)
+		}
+	}
+}
+
+// fakeClamd accepts one INSTREAM session, hands the streamed bytes to verdict
+// and writes back its reply.
+func fakeClamd(t *testing.T, verdict func(data []byte) string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		command := make([]byte, len("zINSTREAM\x00"))
+		if _, err := io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+			conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			return
+		}
+		var data []byte
+		size := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(conn, size); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+			chunk := make([]byte, n)
+			if _, err := io.ReadFull(conn, chunk); err != nil {
+				return
+			}
+			data = append(data, chunk...)
+		}
+		conn.Write([]byte(verdict(data) + "\x00"))
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestClamAVScan(t *testing.T) {
+	eicar := []byte(`X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`)
+	verdict := func(data []byte) string {
+		if bytes.Contains(data, eicar) {
+			return "stream: Eicar-Test-Signature FOUND"
+		}
+		return "stream: OK"
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want Result
+	}{
+		{"clean", []byte("hello"), Result{Clean: true}},
+		{"infected", eicar, Result{Signature: "Eicar-Test-Signature"}},
+		{"spans several chunks", append(bytes.Repeat([]byte("a"), 3*clamavChunkSize), eicar...), Result{Signature: "Eicar-Test-Signature"}},
+		{"empty", nil, Result{Clean: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClamAV(fakeClamd(t, verdict))
+			got, err := c.Scan(context.Background(), bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Scan = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClamAVScanErrors(t *testing.T) {
+	c := NewClamAV(fakeClamd(t, func([]byte) string { return "INSTREAM size limit exceeded. ERROR" }))
+	if _, err := c.Scan(context.Background(), strings.NewReader("data")); err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Errorf("Scan error = %v, want the daemon's error", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	if _, err := NewClamAV(addr).Scan(context.Background(), strings.NewReader("data")); err == nil {
+		t.Error("Scan succeeded without a daemon")
+	}
+}
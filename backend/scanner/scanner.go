@@ -0,0 +1,51 @@
+// Package scanner checks uploaded files for malware before anyone can
+// download them.
+package scanner
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// Result is the verdict on a scanned file.
+type Result struct {
+	Clean bool
+	// Signature names what was found in an infected file.
+	Signature string
+}
+
+// Scanner inspects a file's contents. An error means the file could not be
+// scanned and should be tried again, not that it is infected.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+}
+
+// Nop accepts every file, for setups without a virus scanner.
+type Nop struct{}
+
+func (Nop) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	return Result{Clean: true}, nil
+}
+
+// Default is the scanner used for attachments, set up by Connect.
+var Default Scanner = Nop{}
+
+// Connect configures Default from the environment: SCANNER selects "none"
+// (the default) or "clamav".
+func Connect() {
+	switch kind := strings.ToLower(os.Getenv("SCANNER")); kind {
+	case "", "none":
+		Default = Nop{}
+	case "clamav":
+		address := os.Getenv("CLAMAV_ADDRESS")
+		if address == "" {
+			address = "tcp://localhost:3310"
+		}
+		Default = NewClamAV(address)
+	default:
+		log.Fatalf("Unknown SCANNER %q", kind)
+	}
+}
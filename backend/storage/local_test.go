@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestLocal(t *testing.T) *Local {
+	t.Helper()
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLocal(t)
+
+	for _, key := range []string{"flat", "tasks/1/abc", "tasks/1/abc-thumbnail"} {
+		if err := store.Put(ctx, key, strings.NewReader("contents of "+key), -1, "text/plain"); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+		file, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil || string(data) != "contents of "+key {
+			t.Errorf("Get(%q) = %q, %v", key, data, err)
+		}
+	}
+
+	// Overwrites replace the object.
+	if err := store.Put(ctx, "flat", strings.NewReader("new"), 3, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	file, err := store.Get(ctx, "flat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "new" {
+		t.Errorf("after overwrite Get = %q, want new", data)
+	}
+
+	if err := store.Delete(ctx, "flat"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "flat"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "flat"); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLocal(t)
+	outside := filepath.Join(filepath.Dir(store.dir), "escaped")
+
+	for _, key := range []string{"../escaped", "tasks/../../escaped", "", ".", "tasks/.."} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if _, err := store.Get(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q): error = %v, want an invalid key error", key, err)
+		}
+		if err := store.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded", key)
+		}
+	}
+	if _, err := os.Stat(outside); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("a file was written outside the store: %v", err)
+	}
+}
+
+func TestLocalPutLeavesNoPartialFile(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLocal(t)
+
+	failing := io.MultiReader(strings.NewReader("partial"), errReader{})
+	if err := store.Put(ctx, "tasks/1/broken", failing, -1, "text/plain"); err == nil {
+		t.Fatal("Put with a failing reader succeeded")
+	}
+	if _, err := store.Get(ctx, "tasks/1/broken"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after a failed Put: error = %v, want ErrNotFound", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(store.dir, "tasks", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("left behind %d file(s) after a failed Put", len(entries))
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
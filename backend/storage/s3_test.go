@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// newTestS3 connects to the MinIO or other S3-compatible service named by
+// TEST_S3_ENDPOINT and creates a bucket of its own for t. Without it the test
+// is skipped.
+func newTestS3(t *testing.T) *S3 {
+	t.Helper()
+
+	endpoint := os.Getenv("TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("TEST_S3_ENDPOINT is not set")
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewS3(S3Config{
+		Endpoint:  endpoint,
+		Region:    os.Getenv("TEST_S3_REGION"),
+		Bucket:    "test-" + hex.EncodeToString(suffix),
+		AccessKey: os.Getenv("TEST_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("TEST_S3_SECRET_KEY"),
+		UseSSL:    os.Getenv("TEST_S3_USE_SSL") == "true",
+	})
+	if err != nil {
+		t.Fatalf("connect to test bucket: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx := context.Background()
+		for object := range store.client.ListObjects(ctx, store.bucket, minio.ListObjectsOptions{Recursive: true}) {
+			store.client.RemoveObject(ctx, store.bucket, object.Key, minio.RemoveObjectOptions{})
+		}
+		store.client.RemoveBucket(ctx, store.bucket)
+	})
+	return store
+}
+
+func TestS3RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := newTestS3(t)
+
+	for _, key := range []string{"flat", "tasks/1/abc", "tasks/1/abc-thumbnail"} {
+		if err := store.Put(ctx, key, strings.NewReader("contents of "+key), -1, "text/plain"); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+		file, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil || string(data) != "contents of "+key {
+			t.Errorf("Get(%q) = %q, %v", key, data, err)
+		}
+	}
+
+	// Overwrites replace the object.
+	if err := store.Put(ctx, "flat", strings.NewReader("new"), 3, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	file, err := store.Get(ctx, "flat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "new" {
+		t.Errorf("after overwrite Get = %q, want new", data)
+	}
+
+	if err := store.Delete(ctx, "flat"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "flat"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "flat"); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+}
+
+func TestS3GetMissing(t *testing.T) {
+	store := newTestS3(t)
+
+	if _, err := store.Get(context.Background(), "tasks/1/never-stored"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing object: error = %v, want ErrNotFound", err)
+	}
+}
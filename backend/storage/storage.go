@@ -0,0 +1,62 @@
+// Package storage keeps uploaded files outside the database, either on the
+// local filesystem or in an S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/config"
+)
+
+// ErrNotFound is returned by Get when no object has the key.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Store saves and serves objects by key. Keys are chosen by the caller and
+// may contain slashes.
+type Store interface {
+	// Put stores the contents of r under key. size is -1 when unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object and succeeds if it does not exist.
+	Delete(ctx context.Context, key string) error
+}
+
+// Files is the store used for attachments, set up by Connect.
+var Files Store
+
+// Connect configures Files from the environment: STORAGE_DRIVER selects
+// "local" (the default) or "s3".
+func Connect() {
+	switch driver := strings.ToLower(os.Getenv("STORAGE_DRIVER")); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		local, err := NewLocal(dir)
+		if err != nil {
+			log.Fatal("Failed to set up file storage: ", err)
+		}
+		Files = local
+	case "s3":
+		s3, err := NewS3(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			UseSSL:    config.Bool("S3_USE_SSL", true),
+		})
+		if err != nil {
+			log.Fatal("Failed to set up file storage: ", err)
+		}
+		Files = s3
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER %q", driver)
+	}
+}
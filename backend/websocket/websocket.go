@@ -1,112 +1,267 @@
 package websocket
 
-// import (
-// 	"log"
-// 	"sync"
-
-// 	"github.com/gofiber/websocket/v2"
-// 	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
-// )
-
-// // Client represents a WebSocket client connection
-// type Client struct {
-// 	Conn     *websocket.Conn
-// 	UserID   uint
-// 	IsActive bool
-// 	mu       sync.Mutex
-// }
-
-// // TaskUpdate represents a task update that will be sent via WebSocket
-// type TaskUpdate struct {
-// 	Task   model.Task `json:"task"`
-// 	Action string     `json:"action"` // created, updated, deleted
-// 	UserID uint       `json:"user_id"`
-// }
-
-// // Global variables
-// var (
-// 	clients    = make(map[*Client]bool)
-// 	register   = make(chan *Client)
-// 	unregister = make(chan *Client)
-// 	broadcast  = make(chan TaskUpdate)
-// 	mutex      = &sync.Mutex{}
-// )
-
-// // StartWebSocketHub starts the WebSocket hub
-// func StartWebSocketHub() {
-// 	for {
-// 		select {
-// 		case client := <-register:
-// 			mutex.Lock()
-// 			clients[client] = true
-// 			mutex.Unlock()
-// 			log.Printf("Client connected: %d active connections\n", len(clients))
-
-// 		case client := <-unregister:
-// 			mutex.Lock()
-// 			delete(clients, client)
-// 			mutex.Unlock()
-// 			log.Printf("Client disconnected: %d active connections\n", len(clients))
-
-// 		case update := <-broadcast:
-// 			mutex.Lock()
-// 			for client := range clients {
-// 				// Send update to relevant clients (assigned to or created by)
-// 				if client.UserID == update.Task.AssignedTo || client.UserID == update.Task.CreatedBy {
-// 					client.mu.Lock()
-// 					if client.IsActive {
-// 						err := client.Conn.WriteJSON(update)
-// 						if err != nil {
-// 							log.Printf("WebSocket error: %v", err)
-// 							client.IsActive = false
-// 							unregister <- client
-// 						}
-// 					}
-// 					client.mu.Unlock()
-// 				}
-// 			}
-// 			mutex.Unlock()
-// 		}
-// 	}
-// }
-
-// // HandleWebSocket handles WebSocket connections
-// func HandleWebSocket(c *websocket.Conn) {
-// 	// Get user ID from the context (set by auth middleware)
-// 	userID := c.Locals("user_id").(uint)
-
-// 	// Create new client
-// 	client := &Client{
-// 		Conn:     c,
-// 		UserID:   userID,
-// 		IsActive: true,
-// 	}
-
-// 	// Register client
-// 	register <- client
-
-// 	// Handle disconnection
-// 	defer func() {
-// 		unregister <- client
-// 		c.Close()
-// 	}()
-
-// 	// Read messages (keep connection alive)
-// 	for {
-// 		messageType, _, err := c.ReadMessage()
-// 		if err != nil || messageType == websocket.CloseMessage {
-// 			break
-// 		}
-// 	}
-// }
-
-// // BroadcastTaskUpdate sends a task update to all relevant clients
-// func BroadcastTaskUpdate(task model.Task, action string) {
-// 	update := TaskUpdate{
-// 		Task:   task,
-// 		Action: action,
-// 		UserID: task.AssignedTo,
-// 	}
-
-// 	broadcast <- update
-// }
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+	sendBuffer = 16
+)
+
+// Client represents a WebSocket client connection
+type Client struct {
+	Conn   *websocket.Conn
+	UserID uint
+	// SessionID is empty for connections opened with a personal access token.
+	SessionID string
+	// TokenID is zero for connections opened with a login session.
+	TokenID uint
+	send    chan interface{}
+}
+
+// TaskUpdate represents a task update that will be sent via WebSocket
+type TaskUpdate struct {
+	Type   string     `json:"type"`
+	Task   model.Task `json:"task"`
+	Action string     `json:"action"` // created, updated, deleted, restored
+	UserID uint       `json:"user_id"`
+}
+
+// CommentUpdate represents a comment event on a task
+type CommentUpdate struct {
+	Type    string        `json:"type"`
+	Comment model.Comment `json:"comment"`
+	Action  string        `json:"action"` // created, updated, deleted
+	TaskID  uint          `json:"task_id"`
+}
+
+// NotificationUpdate carries a new notification to its user
+type NotificationUpdate struct {
+	Type         string             `json:"type"`
+	Notification model.Notification `json:"notification"`
+}
+
+// Global variables
+var (
+	clients = make(map[uint]map[*Client]bool)
+	mutex   = &sync.Mutex{}
+
+	// Tokens are passed in the query string rather than cookies, so
+	// cross-origin handshakes cannot act on a user's behalf.
+	upgrader = websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+)
+
+func register(client *Client) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if clients[client.UserID] == nil {
+		clients[client.UserID] = make(map[*Client]bool)
+	}
+	clients[client.UserID][client] = true
+}
+
+func unregister(client *Client) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	remove(client)
+}
+
+// remove drops client from the registry and closes its send channel, which
+// makes writePump close the connection. The caller must hold mutex.
+func remove(client *Client) {
+	if _, ok := clients[client.UserID][client]; !ok {
+		return
+	}
+	delete(clients[client.UserID], client)
+	if len(clients[client.UserID]) == 0 {
+		delete(clients, client.UserID)
+	}
+	close(client.send)
+}
+
+// HandleWebSocket upgrades an authenticated request and streams task updates
+// for the user named in the path until the connection closes.
+func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.PathValue("id") != strconv.FormatUint(uint64(userID), 10) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	sessionID, _ := r.Context().Value(middleware.SessionIDKey).(string)
+	tokenID, _ := r.Context().Value(middleware.TokenIDKey).(uint)
+	client := &Client{
+		Conn:      conn,
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenID:   tokenID,
+		send:      make(chan interface{}, sendBuffer),
+	}
+	register(client)
+
+	go client.writePump()
+	client.readPump()
+}
+
+// readPump discards incoming messages and keeps the connection alive until
+// the client goes away.
+func (c *Client) readPump() {
+	defer func() {
+		unregister(c)
+		c.Conn.Close()
+	}()
+
+	c.Conn.SetReadLimit(512)
+	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		if _, _, err := c.Conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.Conn.WriteJSON(message); err != nil {
+				log.Printf("WebSocket error: %v", err)
+				return
+			}
+		case <-ticker.C:
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// DisconnectUser closes every connection of the user except those opened by
+// the session keepSessionID, for when their sessions are revoked or the
+// account is disabled.
+func DisconnectUser(userID uint, keepSessionID string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for client := range clients[userID] {
+		if keepSessionID == "" || client.SessionID != keepSessionID {
+			remove(client)
+		}
+	}
+}
+
+// DisconnectSession closes the connections opened by a revoked session.
+func DisconnectSession(userID uint, sessionID string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for client := range clients[userID] {
+		if client.SessionID == sessionID {
+			remove(client)
+		}
+	}
+}
+
+// DisconnectToken closes the connections opened with a revoked personal
+// access token.
+func DisconnectToken(userID, tokenID uint) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for client := range clients[userID] {
+		if client.TokenID == tokenID {
+			remove(client)
+		}
+	}
+}
+
+// sendToUsers queues message for every connection of the given users. Slow
+// clients miss messages rather than block the caller.
+func sendToUsers(userIDs []uint, message interface{}) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	seen := make(map[uint]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		for client := range clients[userID] {
+			select {
+			case client.send <- message:
+			default:
+				log.Printf("WebSocket client for user %d is not keeping up, dropping message", userID)
+			}
+		}
+	}
+}
+
+// BroadcastTaskUpdate sends a task update to all relevant clients (assigned
+// to, created by or watching).
+func BroadcastTaskUpdate(task model.Task, action string, watchers []uint) {
+	update := TaskUpdate{
+		Type:   "task",
+		Task:   task,
+		Action: action,
+		UserID: task.AssignedTo,
+	}
+
+	sendToUsers(append([]uint{task.AssignedTo, task.CreatedBy}, watchers...), update)
+}
+
+// BroadcastCommentUpdate sends a comment event to the task's watchers.
+func BroadcastCommentUpdate(comment model.Comment, action string, watchers []uint) {
+	sendToUsers(watchers, CommentUpdate{
+		Type:    "comment",
+		Comment: comment,
+		Action:  action,
+		TaskID:  comment.TaskID,
+	})
+}
+
+// BroadcastNotification delivers a notification to its user's open
+// connections.
+func BroadcastNotification(notification model.Notification) {
+	sendToUsers([]uint{notification.UserID}, NotificationUpdate{
+		Type:         "notification",
+		Notification: notification,
+	})
+}
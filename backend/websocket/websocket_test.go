@@ -0,0 +1,192 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/middleware"
+	"github.com/rudransh-shrivastava/zocket-assignmnet/backend/model"
+)
+
+// dial opens a connection for user 1, authenticated as sessionID.
+func dial(t *testing.T, sessionID string) *websocket.Conn {
+	t.Helper()
+	return connect(t, func(ctx context.Context) context.Context {
+		if sessionID == "" {
+			return ctx
+		}
+		return context.WithValue(ctx, middleware.SessionIDKey, sessionID)
+	}, func() bool { return registered(sessionID) })
+}
+
+// dialToken opens a connection for user 1, authenticated with the personal
+// access token tokenID.
+func dialToken(t *testing.T, tokenID uint) *websocket.Conn {
+	t.Helper()
+	return connect(t, func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, middleware.TokenIDKey, tokenID)
+	}, func() bool { return registeredToken(tokenID) })
+}
+
+// connect opens a connection for user 1 with the credentials auth adds to
+// the request context, and waits until ready reports it registered.
+func connect(t *testing.T, auth func(context.Context) context.Context, ready func() bool) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserIDKey, uint(1))
+		r.SetPathValue("id", "1")
+		HandleWebSocket(w, r.WithContext(auth(ctx)))
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// Wait for the handler to register the connection.
+	deadline := time.Now().Add(time.Second)
+	for !ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("connection was not registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return conn
+}
+
+// registered reports whether user 1 has a connection for sessionID.
+func registered(sessionID string) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for client := range clients[1] {
+		if client.SessionID == sessionID {
+			return true
+		}
+	}
+	return false
+}
+
+// registeredToken reports whether user 1 has a connection for tokenID.
+func registeredToken(tokenID uint) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for client := range clients[1] {
+		if client.TokenID == tokenID {
+			return true
+		}
+	}
+	return false
+}
+
+// closed reports whether the server closes conn within a second.
+func closed(conn *websocket.Conn) bool {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := conn.ReadMessage()
+	_, isClose := err.(*websocket.CloseError)
+	return isClose
+}
+
+func TestDisconnectSession(t *testing.T) {
+	revoked := dial(t, "revoked")
+	dial(t, "kept")
+
+	DisconnectSession(1, "revoked")
+
+	if !closed(revoked) {
+		t.Error("connection of the revoked session is still open")
+	}
+	if !registered("kept") {
+		t.Error("connection of another session was closed")
+	}
+	DisconnectUser(1, "")
+}
+
+func TestDisconnectToken(t *testing.T) {
+	revoked := dialToken(t, 7)
+	dialToken(t, 8)
+	dial(t, "session")
+
+	DisconnectToken(1, 7)
+
+	if !closed(revoked) {
+		t.Error("connection of the revoked token is still open")
+	}
+	if !registeredToken(8) {
+		t.Error("connection of another token was closed")
+	}
+	if !registered("session") {
+		t.Error("connection of a session was closed")
+	}
+	DisconnectUser(1, "")
+}
+
+func TestDisconnectUser(t *testing.T) {
+	current := dial(t, "current")
+	other := dial(t, "other")
+	token := dial(t, "")
+
+	DisconnectUser(1, "current")
+
+	if !closed(other) {
+		t.Error("connection of another session is still open")
+	}
+	if !closed(token) {
+		t.Error("connection opened with a token is still open")
+	}
+	if !registered("current") {
+		t.Error("connection of the kept session was closed")
+	}
+
+	DisconnectUser(1, "")
+	if !closed(current) {
+		t.Error("connection is still open after disconnecting every session")
+	}
+}
+
+func TestHandleWebSocketRequiresUser(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/ws/1", nil)
+	r.SetPathValue("id", "1")
+	HandleWebSocket(rec, r)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/ws/2", nil)
+	r.SetPathValue("id", "2")
+	HandleWebSocket(rec, r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, uint(1))))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("another user's stream: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestBroadcastTaskUpdateReachesInvolvedUsers(t *testing.T) {
+	conn := dial(t, "session")
+	defer DisconnectUser(1, "")
+
+	BroadcastTaskUpdate(model.Task{ID: 10, AssignedTo: 2, CreatedBy: 3}, "updated", []uint{4})
+	BroadcastTaskUpdate(model.Task{ID: 11, AssignedTo: 2, CreatedBy: 3}, "updated", []uint{1, 1})
+
+	var update TaskUpdate
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if err := conn.ReadJSON(&update); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if update.Task.ID != 11 || update.Action != "updated" {
+		t.Errorf("received %+v, want the update for task 11 only", update)
+	}
+
+	// The watcher is listed twice but hears about the task once.
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if err := conn.ReadJSON(&update); err == nil {
+		t.Errorf("received an unexpected %+v", update)
+	}
+}
@@ -168,7 +168,9 @@ function WebSocketProvider({ children }: { children: ReactNode }) {
     }
 
     // Create WebSocket connection
-    const ws = new WebSocket(`ws://localhost:8080/ws/${user.id}`);
+    const ws = new WebSocket(
+      `ws://localhost:8080/ws/${user.id}?token=${encodeURIComponent(token)}`,
+    );
 
     ws.onopen = () => {
       console.log("WebSocket connected");
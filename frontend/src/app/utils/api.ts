@@ -46,19 +46,32 @@ export const fetchTasks = async (): Promise<Task[]> => {
     throw new Error("Not authenticated");
   }
 
-  const response = await fetch(`${API_URL}/tasks`, {
-    headers: {
-      Authorization: `Bearer ${token}`,
-    },
-  });
+  // The API returns tasks a page at a time; follow the cursor to the end.
+  const tasks: Task[] = [];
+  let cursor = "";
+  do {
+    const params = new URLSearchParams({ limit: "200" });
+    if (cursor) {
+      params.set("cursor", cursor);
+    }
+
+    const response = await fetch(`${API_URL}/tasks/?${params}`, {
+      headers: {
+        Authorization: `Bearer ${token}`,
+      },
+    });
 
-  if (!response.ok) {
-    const error = await response.json();
-    throw new Error(error.error || "Failed to fetch tasks");
-  }
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || "Failed to fetch tasks");
+    }
 
-  const data = await response.json();
-  return data.tasks;
+    const data = await response.json();
+    tasks.push(...data.tasks);
+    cursor = data.next_cursor;
+  } while (cursor);
+
+  return tasks;
 };
 
 // Get task by ID
@@ -123,17 +136,22 @@ export const updateTask = async (
     throw new Error("Not authenticated");
   }
 
+  // Sent as a JSON Merge Patch: omitted fields are left alone and null clears
+  // a field, so an empty date input removes the due date.
+  const patch: Record<string, unknown> = { ...task };
   if (task.due_date) {
-    task.due_date = new Date(task.due_date).toISOString();
+    patch.due_date = new Date(task.due_date).toISOString();
+  } else if (task.due_date === "") {
+    patch.due_date = null;
   }
 
   const response = await fetch(`${API_URL}/tasks/${id}`, {
-    method: "PUT",
+    method: "PATCH",
     headers: {
       Authorization: `Bearer ${token}`,
-      "Content-Type": "application/json",
+      "Content-Type": "application/merge-patch+json",
     },
-    body: JSON.stringify(task),
+    body: JSON.stringify(patch),
   });
 
   if (!response.ok) {